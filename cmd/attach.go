@@ -0,0 +1,31 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"mark/internal/logging"
+	"mark/internal/program"
+
+	"github.com/spf13/cobra"
+)
+
+// attachCmd opens a TUI on the instance running in the current project.
+var attachCmd = &cobra.Command{
+	Use:   "attach",
+	Short: "Attach a TUI to the running instance (ctrl+c detaches)",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		logging.Setup()
+
+		err := program.Attach(program.Options{Token: socketToken(), Config: cfg})
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(attachCmd)
+}
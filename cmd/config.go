@@ -0,0 +1,54 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+)
+
+// configCmd groups the commands about the configuration.
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Inspect the configuration",
+}
+
+// configShowCmd prints the effective configuration and where every setting
+// comes from. An invalid configuration is printed too, followed by its
+// errors.
+var configShowCmd = &cobra.Command{
+	Use:   "show",
+	Short: "Print the effective configuration with the source of each setting",
+	Args:  cobra.NoArgs,
+	// the configuration is shown even when it's invalid
+	PersistentPreRun: func(cmd *cobra.Command, args []string) {},
+	Run: func(cmd *cobra.Command, args []string) {
+		cfg, sources, err := loadConfig(cmd)
+		if err != nil && sources == nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "SETTING\tVALUE\tSOURCE")
+		for _, entry := range cfg.Entries(sources) {
+			value := entry.Value
+			if value == "" {
+				value = "(none)"
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\n", entry.Name, value, entry.Source)
+		}
+		w.Flush()
+
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "\nError: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	configCmd.AddCommand(configShowCmd)
+	rootCmd.AddCommand(configCmd)
+}
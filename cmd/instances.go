@@ -0,0 +1,42 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"mark/internal/remote"
+
+	"github.com/spf13/cobra"
+)
+
+// instancesCmd lists the running mark TUIs.
+var instancesCmd = &cobra.Command{
+	Use:   "instances",
+	Short: "List running mark instances",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		instances, err := remote.ListInstances()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		if len(instances) == 0 {
+			fmt.Println("No running instances.")
+			return
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "ROOT\tPID\tSTARTED\tSOCKET")
+		for _, instance := range instances {
+			fmt.Fprintf(w, "%s\t%d\t%s\t%s\n", instance.Root, instance.PID, instance.StartedAt.Local().Format(time.DateTime), instance.Socket)
+		}
+		w.Flush()
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(instancesCmd)
+}
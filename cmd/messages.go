@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"path/filepath"
+	"strings"
 
 	"mark/internal/messages"
 	"mark/internal/remote"
@@ -35,6 +37,15 @@ func init() {
 					os.Exit(1)
 				}
 
+				// the instance may run in another directory of the project
+				if msg.PathArgs {
+					for i, arg := range args {
+						if !filepath.IsAbs(arg) {
+							args[i] = filepath.Join(cwd, arg)
+						}
+					}
+				}
+
 				var stdin string
 				if useStdin {
 					stdinData, err := io.ReadAll(os.Stdin)
@@ -46,11 +57,15 @@ func init() {
 				}
 
 				// Send the message using the client
-				err = client.SendRequest(remote.Request{Command: command, Args: args, Stdin: stdin})
+				output, err := client.Query(remote.Request{Command: command, Args: args, Stdin: stdin, Token: socketToken()})
 				if err != nil {
 					fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 					os.Exit(1)
 				}
+
+				if output != "" {
+					fmt.Println(strings.TrimSuffix(output, "\n"))
+				}
 			},
 		}
 
@@ -6,8 +6,10 @@ import (
 	"fmt"
 	"os"
 
+	"mark/internal/config"
 	"mark/internal/logging"
 	"mark/internal/program"
+	"mark/internal/remote"
 
 	"github.com/spf13/cobra"
 )
@@ -18,10 +20,28 @@ import (
 var rootCmd = &cobra.Command{
 	Use:   "mark",
 	Short: "Mark TUI Assistant",
+	PersistentPreRun: func(cmd *cobra.Command, args []string) {
+		// commands talking to a running instance only need the directory
+		// of its socket, the instance checks the rest
+		var err error
+		cfg, _, err = loadConfig(cmd, "socket_dir")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		remote.SetRuntimeDir(cfg.SocketDir)
+	},
 	Run: func(cmd *cobra.Command, args []string) {
 		logging.Setup()
 
-		program, err := program.NewProgram()
+		cfg, _, err := loadConfig(cmd)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		program, err := program.NewProgram(program.Options{Token: socketToken(), Config: cfg})
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 			os.Exit(1)
@@ -35,6 +55,57 @@ var rootCmd = &cobra.Command{
 	},
 }
 
+// token is required in requests sent to the control socket when not empty.
+var token string
+
+// configFile replaces the user config file when not empty.
+var configFile string
+
+// cfg is the configuration, loaded before any command runs. Only socket_dir
+// is checked, commands using other settings load it again.
+var cfg config.Config
+
+// loadConfig loads the configuration of the project containing the working
+// directory, with the settings given as flags to cmd. Only the settings are
+// checked, every setting when none are given.
+func loadConfig(cmd *cobra.Command, settings ...string) (config.Config, config.Sources, error) {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return config.Config{}, nil, err
+	}
+
+	root, err := remote.ProjectRoot(cwd)
+	if err != nil {
+		return config.Config{}, nil, fmt.Errorf("failed to determine project root: %w", err)
+	}
+
+	flags := map[string]string{}
+	for _, flag := range config.Flags() {
+		if f := cmd.Flags().Lookup(flag.Name); f != nil && f.Changed {
+			flags[flag.Setting] = f.Value.String()
+		}
+	}
+
+	return config.Load(config.Options{
+		UserFile:   configFile,
+		ProjectDir: root,
+		Flags:      flags,
+		Defaults:   program.DefaultConfig(),
+		Validate:   program.ValidateConfig,
+		Settings:   settings,
+	})
+}
+
+// socketToken returns the token given with --token, falling back to the
+// MARK_TOKEN environment variable. The environment is read here instead of
+// being used as the flag default so the token isn't shown in help output.
+func socketToken() string {
+	if token != "" {
+		return token
+	}
+	return os.Getenv("MARK_TOKEN")
+}
+
 // Execute the root command.
 // This is called by main.main().
 func Execute() {
@@ -46,12 +117,16 @@ func Execute() {
 
 // init defines flags and configuration settings.
 func init() {
-	// Cobra supports persistent flags, which, if defined here,
-	// will be global for the application.
+	rootCmd.PersistentFlags().StringVar(&configFile, "config", "", "user config file (default is ~/.config/mark/config.yaml)")
+	rootCmd.PersistentFlags().StringVar(&token, "token", "", "token protecting the control socket (default is $MARK_TOKEN)")
 
-	// rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is $HOME/.mark.yaml)")
-
-	// Cobra also supports local flags, which will only run
-	// when this action is called directly.
-	// rootCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
+	// every setting can be given as a flag, overriding the config files and
+	// the environment
+	for _, flag := range config.Flags() {
+		if flag.Int {
+			rootCmd.PersistentFlags().Int(flag.Name, 0, flag.Usage)
+		} else {
+			rootCmd.PersistentFlags().String(flag.Name, "", flag.Usage)
+		}
+	}
 }
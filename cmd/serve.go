@@ -0,0 +1,46 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"mark/internal/logging"
+	"mark/internal/program"
+
+	"github.com/spf13/cobra"
+)
+
+// serveCmd runs mark without a terminal UI. The session is driven through
+// the control socket, using the same commands available to the TUI.
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Run mark without a TUI, serving the control socket",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		logging.SetupStderr()
+
+		cfg, _, err := loadConfig(cmd)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		program, err := program.NewProgram(program.Options{Token: socketToken(), Headless: true, Config: cfg})
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		fmt.Fprintf(os.Stderr, "Serving on %s\n", program.SocketPath())
+
+		err = program.Run()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %+v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(serveCmd)
+}
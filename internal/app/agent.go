@@ -3,6 +3,9 @@ package app
 import (
 	"context"
 	"log/slog"
+	"strings"
+	"sync"
+	"time"
 
 	"mark/internal/domain"
 	"mark/internal/llm"
@@ -13,76 +16,229 @@ import (
 	tea "github.com/charmbracelet/bubbletea/v2"
 )
 
+// DefaultFrameRate is the number of times per second the reply is updated
+// while it's streamed.
+const DefaultFrameRate = 30
+
+// runID identifies a run of the agent. Messages of a run are tagged with its
+// ID, so the App can drop the ones of a run that was cancelled or replaced.
+type runID uint64
+
+// Agent runs the provider for the App. Run is called from a command while
+// Cancel is called from Update, so the state of the current run is guarded
+// by mu.
 type Agent struct {
-	provider  provider.Provider
-	cancel    context.CancelFunc // cancels the current streaming request
-	streaming bool               // true if the agent is currently streaming
-	events    chan tea.Msg       // sends tea.Msg to the main app
-	logger    *slog.Logger
+	provider      provider.Provider
+	retry         provider.RetryPolicy // retries the requests failing before streaming
+	events        chan tea.Msg         // sends tea.Msg to the main app
+	frameInterval time.Duration        // chunks received in this interval are sent together
+	logger        *slog.Logger
+
+	mu     sync.Mutex
+	run    runID              // the current or last run
+	cancel context.CancelFunc // cancels the current streaming request
 }
 
 func NewAgent(events chan tea.Msg) *Agent {
-	return &Agent{
+	agent := &Agent{
 		provider: providers.NewOpenAIClient(),
+		retry:    provider.DefaultRetryPolicy(),
 		events:   events,
 		logger:   logging.NewLogger("agent"),
 	}
+	agent.SetFrameRate(DefaultFrameRate)
+
+	return agent
+}
+
+// SetFrameRate sets how many times per second the streamed reply is sent to
+// the App. Zero or less uses DefaultFrameRate.
+func (agent *Agent) SetFrameRate(fps int) {
+	if fps <= 0 {
+		fps = DefaultFrameRate
+	}
+	agent.frameInterval = time.Second / time.Duration(fps)
+}
+
+// keyedProvider is a provider whose API key can be replaced.
+type keyedProvider interface {
+	WithAPIKey(key string) provider.Provider
+}
+
+// SetProvider replaces the provider used by the next runs.
+func (agent *Agent) SetProvider(p provider.Provider) {
+	agent.mu.Lock()
+	defer agent.mu.Unlock()
+
+	agent.provider = p
+}
+
+// SetAPIKey replaces the provider with a copy authenticating with key, when
+// the provider supports it. The run in progress keeps the provider it
+// started with.
+func (agent *Agent) SetAPIKey(key string) {
+	agent.mu.Lock()
+	defer agent.mu.Unlock()
+
+	if p, ok := agent.provider.(keyedProvider); ok {
+		agent.provider = p.WithAPIKey(key)
+	}
 }
 
 func convertSessionToMessages(session domain.Session) []llm.Message {
 	var messages []llm.Message
 
+	// the system prompt comes first
+	if prompt := session.Persona().Prompt; prompt != "" {
+		messages = append(messages, llm.Message{
+			Role:    llm.RoleSystem,
+			Content: prompt,
+		})
+	}
+
 	// add context message
-	messages = append(messages, llm.Message{
-		Role:    llm.RoleUser,
-		Content: session.Context().Message(),
-	})
+	if len(session.Context().Items()) > 0 || session.Prompt() == "" {
+		messages = append(messages, llm.Message{
+			Role:    llm.RoleUser,
+			Content: session.Context().Message(),
+		})
+	}
+
+	// add the prompt as its own message
+	if session.Prompt() != "" {
+		messages = append(messages, llm.Message{
+			Role:    llm.RoleUser,
+			Content: session.Prompt(),
+		})
+	}
 
 	return messages
 }
 
-func (agent *Agent) Run(session domain.Session) error {
-	if agent.streaming {
-		agent.Cancel()
+// SetMaxRetries sets how many times a failed request is retried. Zero uses
+// the default, a negative number disables retries.
+func (agent *Agent) SetMaxRetries(retries int) {
+	agent.mu.Lock()
+	defer agent.mu.Unlock()
+
+	agent.retry.MaxRetries = retries
+	if retries == 0 {
+		agent.retry.MaxRetries = provider.DefaultRetryPolicy().MaxRetries
 	}
+}
 
+// Run streams the reply to messages, cancelling the run in progress. Every
+// message sent to the App is tagged with run.
+func (agent *Agent) Run(run runID, messages []llm.Message) error {
 	ctx, cancel := context.WithCancel(context.Background())
-	agent.cancel = cancel
+	defer cancel()
 
-	agent.streaming = true
+	agent.mu.Lock()
+	if agent.cancel != nil {
+		agent.cancel()
+	}
+	agent.run = run
+	agent.cancel = cancel
+	retrying := provider.WithRetry(agent.provider, agent.retry)
+	agent.mu.Unlock()
 
-	messages := convertSessionToMessages(session)
+	defer agent.finish(run)
 
-	streamingEvents, err := agent.provider.CompleteStreaming(ctx, messages)
+	streamingEvents, err := retrying.CompleteStreaming(ctx, messages)
 	if err != nil {
 		return err
 	}
 
-	agent.events <- streamStarted{}
+	agent.send(run, streamStarted{})
 
-	for streamingEvent := range streamingEvents {
-		switch e := streamingEvent.(type) {
-		case provider.StreamEventChunk:
-			agent.logger.Info("Received StreamEventChunk", slog.String("chunk", e.Chunk)) // TODO should be debug
-			agent.events <- streamChunkReceived(e.Chunk)
+	ticker := time.NewTicker(agent.frameInterval)
+	defer ticker.Stop()
+	agent.forward(run, streamingEvents, ticker.C)
 
-		case provider.StreamEventError:
-			agent.logger.Error("Received StreamEventError", slog.String("error", e.Error.Error()))
-			agent.events <- ErrMsg{Err: e.Error}
+	return nil
+}
 
-		case provider.StreamEventEnd:
-			agent.logger.Info("Received StreamEventEnd")
-			agent.events <- streamFinished(e.Message)
-		}
+// finish clears the state of run, unless another run replaced it.
+func (agent *Agent) finish(run runID) {
+	agent.mu.Lock()
+	defer agent.mu.Unlock()
+
+	if agent.run == run {
+		agent.cancel = nil
 	}
+}
 
-	agent.streaming = false
+// send sends a message of run to the App.
+func (agent *Agent) send(run runID, msg tea.Msg) {
+	agent.events <- streamMsg{run: run, msg: msg}
+}
 
-	return nil
+// forward sends the streaming events to the App. Chunks are coalesced into
+// one message per frame, a frame ending at every tick of frames, so a fast
+// stream doesn't cause an update of the UI per chunk. The last chunks are
+// sent before the end of the stream, and the end carries the whole reply, so
+// the final content is exact.
+func (agent *Agent) forward(run runID, streamingEvents <-chan provider.StreamingEvent, frames <-chan time.Time) {
+	var pending strings.Builder // chunks received since the last frame
+	var count int               // number of chunks in pending
+
+	flush := func() {
+		if count > 0 {
+			agent.send(run, streamChunkReceived{text: pending.String(), chunks: count})
+			pending.Reset()
+			count = 0
+		}
+	}
+
+	for {
+		select {
+		case <-frames:
+			flush()
+
+		case streamingEvent, ok := <-streamingEvents:
+			if !ok {
+				flush()
+				return
+			}
+
+			switch e := streamingEvent.(type) {
+			case provider.StreamEventChunk:
+				agent.logger.Debug("Received StreamEventChunk", slog.String("chunk", e.Chunk))
+				pending.WriteString(e.Chunk)
+				count++
+
+			case provider.StreamEventRetry:
+				agent.logger.Warn("Retrying request",
+					slog.Int("attempt", e.Attempt),
+					slog.Duration("delay", e.Delay),
+					slog.String("error", e.Err.Error()))
+				agent.send(run, streamRetrying(e))
+
+			case provider.StreamEventError:
+				agent.logger.Error("Received StreamEventError", slog.String("error", e.Error.Error()))
+				flush()
+				agent.send(run, ErrMsg{Err: e.Error})
+				return
+
+			case provider.StreamEventEnd:
+				agent.logger.Info("Received StreamEventEnd",
+					slog.Int("input_tokens", e.Usage.InputTokens),
+					slog.Int("output_tokens", e.Usage.OutputTokens))
+				flush()
+				if e.Usage != (provider.Usage{}) {
+					agent.send(run, streamUsageReceived(e.Usage))
+				}
+				agent.send(run, streamFinished(e.Message))
+				return
+			}
+		}
+	}
 }
 
+// Cancel stops the run in progress.
 func (agent *Agent) Cancel() {
-	agent.streaming = false
+	agent.mu.Lock()
+	defer agent.mu.Unlock()
 
 	if agent.cancel != nil {
 		agent.cancel()
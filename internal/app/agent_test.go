@@ -0,0 +1,233 @@
+package app
+
+import (
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"mark/internal/llm/provider"
+	"mark/internal/llm/provider/providertest"
+
+	tea "github.com/charmbracelet/bubbletea/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// wordsScript returns a script streaming the words of reply.
+func wordsScript(reply string) providertest.Script {
+	script := providertest.Words(reply)
+	script.Usage = provider.Usage{InputTokens: 5, OutputTokens: len(script.Chunks)}
+	return script
+}
+
+// runFakeAgent runs an agent with p and returns the messages sent to the App.
+func runFakeAgent(t *testing.T, p provider.Provider, fps int) []tea.Msg {
+	t.Helper()
+
+	events := make(chan tea.Msg)
+	agent := NewAgent(events)
+	agent.provider = p
+	agent.SetFrameRate(fps)
+
+	done := make(chan error, 1)
+	go func() {
+		done <- agent.Run(7, nil)
+		close(events)
+	}()
+
+	var msgs []tea.Msg
+	for msg := range events {
+		stream, ok := msg.(streamMsg)
+		require.True(t, ok, "untagged message %#v", msg)
+		assert.Equal(t, runID(7), stream.run)
+		msgs = append(msgs, stream.msg)
+	}
+	require.NoError(t, <-done)
+
+	return msgs
+}
+
+// receive returns the next value of ch, failing when nothing comes.
+func receive[T any](t *testing.T, ch <-chan T) T {
+	t.Helper()
+
+	select {
+	case v := <-ch:
+		return v
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for a value")
+		var zero T
+		return zero
+	}
+}
+
+func TestAgent(t *testing.T) {
+	t.Parallel()
+
+	reply := strings.Repeat("streamed words ", 50)
+
+	t.Run("coalesces the chunks of a frame", func(t *testing.T) {
+		t.Parallel()
+
+		// a frame is longer than the whole stream
+		msgs := runFakeAgent(t, providertest.NewScripted(wordsScript(reply)), 1)
+
+		assert.Equal(t, []tea.Msg{
+			streamStarted{},
+			streamChunkReceived{text: reply, chunks: 100},
+			streamUsageReceived{InputTokens: 5, OutputTokens: 100},
+			streamFinished(reply),
+		}, msgs)
+	})
+
+	t.Run("sends a message per frame", func(t *testing.T) {
+		t.Parallel()
+
+		events := make(chan tea.Msg)
+		agent := NewAgent(events)
+
+		stream := make(chan provider.StreamingEvent)
+		frames := make(chan time.Time)
+		go agent.forward(7, stream, frames)
+
+		// every send returns once forward received it
+		stream <- provider.StreamEventChunk{Chunk: "Hello"}
+		stream <- provider.StreamEventChunk{Chunk: ", "}
+		frames <- time.Time{}
+		assert.Equal(t, streamMsg{run: 7, msg: streamChunkReceived{text: "Hello, ", chunks: 2}}, receive(t, events))
+
+		// frames without chunks send nothing
+		frames <- time.Time{}
+		stream <- provider.StreamEventChunk{Chunk: "world"}
+		frames <- time.Time{}
+		assert.Equal(t, streamMsg{run: 7, msg: chunkMsg("world")}, receive(t, events))
+
+		stream <- provider.StreamEventChunk{Chunk: "!"}
+		stream <- provider.StreamEventEnd{Message: "Hello, world!"}
+		assert.Equal(t, streamMsg{run: 7, msg: chunkMsg("!")}, receive(t, events))
+		assert.Equal(t, streamMsg{run: 7, msg: streamFinished("Hello, world!")}, receive(t, events))
+	})
+
+	t.Run("sends the chunks before an error", func(t *testing.T) {
+		t.Parallel()
+
+		err := assert.AnError
+		msgs := runFakeAgent(t, providertest.NewScripted(providertest.Script{Chunks: []string{"partial"}, Err: err}), 1)
+
+		assert.Equal(t, []tea.Msg{streamStarted{}, chunkMsg("partial"), ErrMsg{Err: err}}, msgs)
+	})
+
+	t.Run("reports the retries of the request", func(t *testing.T) {
+		t.Parallel()
+
+		failure := &provider.APIError{StatusCode: http.StatusTooManyRequests, RetryAfter: time.Millisecond, Err: assert.AnError}
+		msgs := runFakeAgent(t, providertest.NewScripted(
+			providertest.Script{Err: failure},
+			providertest.Script{Chunks: []string{"Hello"}},
+		), 1)
+
+		assert.Equal(t, []tea.Msg{
+			streamStarted{},
+			streamRetrying{Attempt: 2, MaxAttempts: 4, Delay: time.Millisecond, Err: failure},
+			chunkMsg("Hello"),
+			streamFinished("Hello"),
+		}, msgs)
+	})
+
+	t.Run("reports an empty response", func(t *testing.T) {
+		t.Parallel()
+
+		msgs := runFakeAgent(t, providertest.NewScripted(providertest.Script{}), 1)
+
+		assert.Equal(t, []tea.Msg{streamStarted{}, ErrMsg{Err: provider.ErrEmptyResponse}}, msgs)
+	})
+
+	t.Run("a new run replaces the run in progress", func(t *testing.T) {
+		t.Parallel()
+
+		events := make(chan tea.Msg)
+		agent := NewAgent(events)
+		agent.provider = providertest.NewScripted(providertest.Script{Chunks: []string{"Hello"}, Hang: true})
+
+		started := make(chan runID)
+		runs := map[runID]bool{}
+		received := make(chan struct{})
+		go func() {
+			defer close(received)
+			for msg := range events {
+				stream := msg.(streamMsg)
+				runs[stream.run] = true
+				if _, ok := stream.msg.(streamStarted); ok {
+					started <- stream.run
+				}
+			}
+		}()
+
+		var wg sync.WaitGroup
+		for run := range runID(3) {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				assert.NoError(t, agent.Run(run+1, nil))
+			}()
+			// the run streams until the next one replaces it
+			assert.Equal(t, run+1, <-started)
+		}
+
+		agent.Cancel()
+		wg.Wait()
+		close(events)
+		<-received
+		assert.Len(t, runs, 3)
+	})
+}
+
+func TestStaleRuns(t *testing.T) {
+	t.Parallel()
+
+	t.Run("messages of the current run are handled", func(t *testing.T) {
+		t.Parallel()
+
+		app := bareApp(t)
+		app = update(app, RunMsg{})
+		app = update(app, streamMsg{run: app.run, msg: chunkMsg("current")})
+
+		assert.Equal(t, "current", app.session.Reply())
+	})
+
+	t.Run("messages of a replaced run are dropped", func(t *testing.T) {
+		t.Parallel()
+
+		app := bareApp(t)
+		app = update(app, RunMsg{})
+		stale := app.run
+		app = update(app, RunMsg{})
+		require.NotEqual(t, stale, app.run)
+
+		app = update(app, streamMsg{run: app.run, msg: streamStarted{}})
+		app = update(app, streamMsg{run: stale, msg: chunkMsg("stale")})
+		app = update(app, streamMsg{run: app.run, msg: chunkMsg("current")})
+		app = update(app, streamMsg{run: stale, msg: streamFinished("stale")})
+
+		assert.Equal(t, "current", app.session.Reply())
+		assert.True(t, app.running)
+	})
+
+	t.Run("messages of a cancelled run are dropped", func(t *testing.T) {
+		t.Parallel()
+
+		app := bareApp(t)
+		app = update(app, RunMsg{})
+		run := app.run
+		app = update(app, streamMsg{run: run, msg: chunkMsg("partial")})
+		app = update(app, keyPress(tea.KeyEscape))
+
+		app = update(app, streamMsg{run: run, msg: chunkMsg(" more")})
+		app = update(app, streamMsg{run: run, msg: ErrMsg{Err: assert.AnError}})
+
+		assert.Equal(t, "partial", app.session.Reply())
+		assert.Nil(t, app.dialog)
+	})
+}
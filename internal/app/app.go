@@ -2,28 +2,97 @@ package app
 
 import (
 	"fmt"
-	"log"
+	"log/slog"
+	"path/filepath"
+	"strings"
+	"time"
 
 	"mark/internal/domain"
+	"mark/internal/files"
+	"mark/internal/llm/provider"
+	"mark/internal/logging"
+	"mark/internal/markdown"
+	"mark/internal/persona"
 	"mark/internal/util"
 
+	"github.com/charmbracelet/bubbles/v2/textinput"
 	tea "github.com/charmbracelet/bubbletea/v2"
-	"github.com/charmbracelet/glamour"
 	"github.com/charmbracelet/lipgloss/v2"
 )
 
 type (
-	eventMsg              struct{ msg tea.Msg }
-	streamStarted         struct{}
-	streamChunkReceived   string
+	eventMsg  struct{ msg tea.Msg }
+	streamMsg struct {
+		run runID
+		msg tea.Msg
+	}
+	streamStarted       struct{}
+	streamChunkReceived struct {
+		text   string
+		chunks int // number of chunks of the provider in text
+	}
 	streamFinished        string
+	streamUsageReceived   provider.Usage
+	streamRetrying        provider.StreamEventRetry
 	AddContextItemTextMsg string
 	AddContextItemFileMsg string
 	RunMsg                struct{}
 	NewSessionMsg         struct{}
 	ErrMsg                struct{ Err error }
+	runFailed             struct{ err error } // an ErrMsg of the current run
+	filesIndexed          struct {
+		paths []string
+		err   error
+	}
 )
 
+// Result is the outcome of a message sent by a remote client.
+type Result struct {
+	Output string
+	Err    error
+}
+
+// Query is implemented by messages that produce a result for whoever sent
+// them, like a remote client.
+type Query interface {
+	Result() <-chan Result
+}
+
+// ReplyQueryMsg asks for the reply of the session. The reply is sent once
+// no run is in progress, so scripts can wait for the agent to finish. The
+// error of the last run is sent along with it.
+type ReplyQueryMsg struct {
+	result chan Result
+}
+
+func NewReplyQueryMsg() ReplyQueryMsg {
+	return ReplyQueryMsg{result: make(chan Result, 1)}
+}
+
+func (msg ReplyQueryMsg) Result() <-chan Result {
+	return msg.result
+}
+
+// CommandMsg wraps a message sent by a remote client, which waits for the
+// message to be handled to learn whether it failed.
+type CommandMsg struct {
+	Msg    tea.Msg
+	result chan Result
+}
+
+func NewCommandMsg(msg tea.Msg) CommandMsg {
+	return CommandMsg{Msg: msg, result: make(chan Result, 1)}
+}
+
+func (msg CommandMsg) Result() <-chan Result {
+	return msg.result
+}
+
+// Done reports that the message was handled, failing with err when not nil.
+func (msg CommandMsg) Done(err error) {
+	msg.result <- Result{Err: err}
+}
+
 var (
 	textColor  = lipgloss.NoColor{}
 	focusColor = lipgloss.Color("2")
@@ -39,30 +108,128 @@ var (
 
 // TODO: rename App to Model
 type App struct {
+	cwd     string
 	session domain.Session
 
 	agent  *Agent
 	events chan tea.Msg
-
-	uiReady bool
-	width   int
-	height  int
-	main    *Main
-	dialog  Component
+	keys   *KeyMap
+	logger *slog.Logger
+
+	renderer *markdown.Renderer // renders the reply
+
+	personas       []persona.Persona
+	systemPrompt   string          // sent when no persona is selected
+	defaultPersona persona.Persona // persona of new sessions
+
+	running      bool            // true while a run is in progress
+	runs         runID           // number of runs started, the ID of the last one
+	run          runID           // run whose messages are shown, 0 when none
+	runErr       error           // error of the last run, nil if it didn't fail
+	replyQueries []ReplyQueryMsg // waiting for the current run to finish
+
+	uiReady       bool
+	width         int
+	height        int
+	main          *Main
+	status        *StatusBar
+	notifications *Notifications
+	dialog        Component
 }
 
 func MakeApp(cwd string, events chan tea.Msg) (App, error) {
 	// init app
+	agent := NewAgent(events)
 	app := App{
-		agent:   NewAgent(events),
-		main:    NewMain(),
-		session: domain.MakeSession(),
-		events:  events,
+		cwd:           cwd,
+		agent:         agent,
+		main:          NewMain(),
+		status:        NewStatusBar(agent.provider.Name(), agent.provider.Model()),
+		notifications: NewNotifications(),
+		session:       domain.MakeSession(),
+		events:        events,
+		logger:        logging.NewLogger("app"),
+		renderer:      markdown.NewRenderer(),
 	}
+	app.SetKeyMap(DefaultKeyMap())
 
 	return app, nil
 }
 
+// SetKeyMap replaces the key bindings of the App.
+func (m *App) SetKeyMap(keys *KeyMap) {
+	m.keys = keys
+	m.main.contextItemsList.SetKeyMap(keys.Context)
+	m.main.prompt.SetKeyMap(keys.Prompt)
+}
+
+// SetProvider sets the provider used by the agent.
+func (m *App) SetProvider(p provider.Provider) {
+	m.agent.SetProvider(p)
+	m.status.provider = p.Name()
+	m.status.model = p.Model()
+}
+
+// SetPersonas sets the personas to choose from and selects the one named
+// selected, for the session and the new ones. The system prompt is sent when
+// no persona is selected. Returns an error if there's no persona named
+// selected.
+func (m *App) SetPersonas(personas []persona.Persona, systemPrompt, selected string) error {
+	m.personas = personas
+	m.systemPrompt = systemPrompt
+	m.defaultPersona = persona.Persona{Prompt: systemPrompt}
+
+	if selected != "" {
+		p, ok := persona.Find(personas, selected)
+		if !ok {
+			return fmt.Errorf("unknown persona %q", selected)
+		}
+		m.defaultPersona = p
+	}
+
+	m.setPersona(m.defaultPersona)
+	return nil
+}
+
+// setPersona sets the persona of the session.
+func (m *App) setPersona(p persona.Persona) {
+	m.session.SetPersona(p)
+	m.status.persona = p.Name
+}
+
+// showPersonaPicker lets the user choose the persona of the session.
+func (m *App) showPersonaPicker() {
+	none := persona.Persona{Prompt: m.systemPrompt}
+
+	m.showDialog(NewPersonaPicker(none, m.personas, m.session.Persona().Name, m.keys.Personas, func(app *App, p persona.Persona) tea.Cmd {
+		app.setPersona(p)
+		if p.Name == "" {
+			return app.toast(levelInfo, "No persona")
+		}
+		return app.toast(levelInfo, "Persona "+p.Name)
+	}))
+}
+
+// SetTheme sets the style of the rendered reply, one of markdown.Styles.
+func (m *App) SetTheme(theme string) {
+	m.renderer.SetStyle(theme)
+}
+
+// SetFrameRate sets how many times per second a streamed reply is updated.
+func (m *App) SetFrameRate(fps int) {
+	m.agent.SetFrameRate(fps)
+}
+
+// SetMaxRetries sets how many times a failed request is retried.
+func (m *App) SetMaxRetries(retries int) {
+	m.agent.SetMaxRetries(retries)
+}
+
+// SetSocketPath sets the path of the control socket shown in the status bar.
+func (m *App) SetSocketPath(path string) {
+	m.status.SetSocketPath(path)
+}
+
 func (m App) Init() tea.Cmd {
 	return processEvents(m.events)
 }
@@ -76,10 +243,35 @@ func (m App) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	msg, cmd := m.processEventMessage(msg)
 	cmds = append(cmds, cmd)
 
+	// the client of a remote command is told once it's handled
+	command, isCommand := msg.(CommandMsg)
+	if isCommand {
+		msg = command.Msg
+	}
+	var commandErr error
+
+	if cmd != nil {
+		if text, ok := remoteCommandText(msg); ok {
+			cmds = append(cmds, m.toast(levelInfo, text))
+		}
+	}
+
+	// drop the messages of runs that were cancelled or replaced
+	msg, ok := m.processStreamMessage(msg)
+	if !ok {
+		return m, tea.Batch(cmds...)
+	}
+
 	// handle messages
 	switch msg := msg.(type) {
 	case ErrMsg:
 		m.handleError(msg.Err)
+		commandErr = msg.Err
+
+	case runFailed:
+		m.handleError(msg.err)
+		m.runErr = msg.err
+		m.setRunning(false)
 
 	case tea.WindowSizeMsg:
 		m.handleWindowSize(msg.Width, msg.Height)
@@ -88,11 +280,23 @@ func (m App) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.session.ClearReply()
 
 	case streamChunkReceived:
-		m.session.AppendChunk(string(msg))
+		m.session.AppendChunk(msg.text)
+		m.status.chunksReceived(msg.chunks)
 		scrollMessages = true
 
+	case streamUsageReceived:
+		m.status.setUsage(provider.Usage(msg))
+
+	case streamRetrying:
+		m.status.retrying(provider.StreamEventRetry(msg))
+		cmds = append(cmds, m.toast(levelWarning, fmt.Sprintf("Retrying in %s (%d/%d): %v", msg.Delay, msg.Attempt, msg.MaxAttempts, msg.Err)))
+
 	case streamFinished:
 		m.session.SetReply(string(msg))
+		if m.running {
+			m.setRunning(false)
+			cmds = append(cmds, m.toast(levelInfo, "Run finished in "+m.status.elapsed().Round(100*time.Millisecond).String()))
+		}
 
 	case AddContextItemTextMsg:
 		m.addContextItem(domain.TextItem(string(msg)))
@@ -101,6 +305,7 @@ func (m App) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		item, err := domain.FileItem(string(msg))
 		if err != nil {
 			m.handleError(err)
+			commandErr = err
 			break
 		}
 		m.addContextItem(item)
@@ -110,9 +315,21 @@ func (m App) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 	case NewSessionMsg:
 		m.newSession()
+
+	case editorFinishedMsg:
+		m.handleEditorFinished(msg)
+
+	case filesIndexed:
+		m.showAddContextFileDialog(msg)
+
+	case ReplyQueryMsg:
+		m.replyQueries = append(m.replyQueries, msg)
+		m.answerReplyQueries()
 	}
 
 	// delegate to component update
+	cmds = append(cmds, m.status.Update(&m, msg))
+	cmds = append(cmds, m.notifications.Update(&m, msg))
 	if m.dialog != nil {
 		cmd := m.dialog.Update(&m, msg)
 		cmds = append(cmds, cmd)
@@ -127,6 +344,10 @@ func (m App) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.main.messagesViewport.GotoBottom()
 	}
 
+	if isCommand {
+		command.Done(commandErr)
+	}
+
 	return m, tea.Batch(cmds...)
 }
 
@@ -137,7 +358,8 @@ func (m App) View() string {
 
 	var view string
 
-	view += m.main.View()
+	view += m.main.View() + "\n" + m.status.View()
+	view = m.placeToast(view)
 
 	if m.dialog != nil {
 		dialogView := m.dialog.View()
@@ -160,19 +382,45 @@ func (m *App) showDialog(dialog Component) {
 }
 
 func (m *App) showAddContextDialog() {
-	m.showDialog(NewInputDialog(func(v string) error {
-		m.addContextItem(domain.TextItem(v))
+	m.showDialog(NewInputDialog(func(app *App, v string) error {
+		app.addContextItem(domain.TextItem(v))
 		return nil
 	}))
 }
 
-func (m *App) showAddContextFileDialog() {
-	m.showDialog(NewInputDialog(func(v string) error {
-		item, err := domain.FileItem(v)
-		if err != nil {
-			return err
+// indexFiles lists the files of the project in the background, the file
+// picker opening once they're listed.
+func (m *App) indexFiles() tea.Cmd {
+	cwd := m.cwd
+	return func() tea.Msg {
+		paths, err := files.Index(cwd)
+		return filesIndexed{paths: paths, err: err}
+	}
+}
+
+func (m *App) showAddContextFileDialog(msg filesIndexed) {
+	if msg.err != nil {
+		m.handleError(msg.err)
+		return
+	}
+	if m.dialog != nil {
+		return // another dialog opened while the files were listed
+	}
+
+	m.showDialog(NewFilePicker(msg.paths, m.keys.Picker, func(paths []string) error {
+		// check every file before adding any of them
+		var items []domain.ContextItem
+		for _, path := range paths {
+			item, err := domain.FileItem(filepath.Join(m.cwd, filepath.FromSlash(path)))
+			if err != nil {
+				return err
+			}
+			items = append(items, item)
+		}
+
+		for _, item := range items {
+			m.addContextItem(item)
 		}
-		m.addContextItem(item)
 		return nil
 	}))
 }
@@ -193,59 +441,193 @@ func (m App) processEventMessage(msg tea.Msg) (tea.Msg, tea.Cmd) {
 	}
 }
 
+// processStreamMessage extracts the message of a run. Returns false if the
+// run isn't the current one.
+func (m App) processStreamMessage(msg tea.Msg) (tea.Msg, bool) {
+	stream, ok := msg.(streamMsg)
+	if !ok {
+		return msg, true
+	}
+
+	if stream.run != m.run {
+		m.logger.Debug("Dropping message of a stale run", slog.Uint64("run", uint64(stream.run)), slog.Uint64("current", uint64(m.run)))
+		return nil, false
+	}
+
+	// only the errors of the current run end it
+	if msg, ok := stream.msg.(ErrMsg); ok {
+		return runFailed{err: msg.Err}, true
+	}
+
+	return stream.msg, true
+}
+
 func (m *App) newSession() {
-	m.agent.Cancel()
+	m.cancelRun()
 
 	m.session = domain.MakeSession()
+	m.runErr = nil
+	m.setPersona(m.defaultPersona)
 
 	m.main.contextItemsList.SetItemsFromSessionContextItems(m.session.Context().Items())
 }
 
+// renderMessagesView renders the reply in the messages pane. The renderer
+// caches its output, so this is cheap when nothing changed.
 func (m *App) renderMessagesView() {
-	// create a new glamour renderer
-	renderer, err := glamour.NewTermRenderer(
-		glamour.WithAutoStyle(),
-		glamour.WithWordWrap(m.main.messagesViewport.Width()-2-2), // 2 is the glamour internal gutter, extra 2 for the right side
-	)
-	if err != nil {
-		m.handleError(fmt.Errorf("failed to create glamour renderer: %w", err))
-		return
-	}
+	width := m.main.messagesViewport.Width() - 2 - 2 // 2 is the glamour internal gutter, extra 2 for the right side
 
 	var content string
 
 	// render the assistant message
 	assistantMessage := m.session.Reply()
 	if assistantMessage != "" {
-		c, err := renderer.Render(assistantMessage)
+		c, err := m.renderer.Render(assistantMessage, width)
 		if err != nil {
-			log.Fatal(err)
+			m.handleError(err)
+			return
 		}
 
 		content += c
 	}
 
-	m.main.messagesViewport.SetContent(content)
+	m.main.setMessagesContent(content)
 }
 
 func (m *App) submitMessage() tea.Cmd {
 	return runAgent(m)
 }
 
+// submitPrompt sets the prompt of the session and runs the agent.
+func (m *App) submitPrompt(prompt string) tea.Cmd {
+	m.session.SetPrompt(prompt)
+	return runAgent(m)
+}
+
 func (app *App) deleteContextItem(index int) {
 	app.session.Context().DeleteItem(index)
 	app.main.contextItemsList.SetItemsFromSessionContextItems(app.session.Context().Items())
 }
 
+// cancelRun stops the agent if a run is in progress.
+func (m *App) cancelRun() {
+	if m.running {
+		m.notify(levelInfo, "Run cancelled")
+	}
+	m.agent.Cancel()
+	m.run = 0
+	m.setRunning(false)
+}
+
+func (m *App) setRunning(running bool) {
+	m.running = running
+	if !running {
+		m.status.stop()
+	}
+	m.answerReplyQueries()
+}
+
+// answerReplyQueries sends the reply to pending queries unless a run is in
+// progress.
+func (m *App) answerReplyQueries() {
+	if m.running {
+		return
+	}
+
+	for _, query := range m.replyQueries {
+		query.result <- Result{Output: m.session.Reply(), Err: m.runErr}
+	}
+	m.replyQueries = nil
+}
+
+// copyReply copies the markdown of the reply to the clipboard.
+func (m *App) copyReply() tea.Cmd {
+	reply := m.session.Reply()
+	if reply == "" {
+		return nil
+	}
+
+	m.logger.Info("Copying reply", slog.Int("length", len(reply)))
+	return copyToClipboard(reply)
+}
+
+// copyCodeBlock copies a code block of the reply to the clipboard, asking
+// which one when there are several.
+func (m *App) copyCodeBlock() tea.Cmd {
+	blocks := markdown.CodeBlocks(m.session.Reply())
+
+	copyBlock := func(app *App, block markdown.CodeBlock) tea.Cmd {
+		app.logger.Info("Copying code block", slog.String("language", block.Language))
+		return copyToClipboard(block.Code)
+	}
+
+	switch len(blocks) {
+	case 0:
+		return nil
+	case 1:
+		return copyBlock(m, blocks[0])
+	}
+
+	m.showDialog(NewCodeBlockPicker("Copy code block", blocks, m.keys.CodeBlocks, copyBlock))
+	return nil
+}
+
+// editContextItem opens the item at index in the editor. Text items are
+// replaced with the edited text, files are edited in place.
+func (app *App) editContextItem(index int) tea.Cmd {
+	items := app.session.Context().Items()
+	if index < 0 || index >= len(items) {
+		return nil
+	}
+
+	switch item := items[index].(type) {
+	case domain.ContextItemText:
+		return editText(item.Message(), app.replaceContextItem(item))
+	case domain.ContextItemFile:
+		return editFile(item.Path())
+	}
+
+	return nil
+}
+
+// replaceContextItem returns a function replacing item with the edited text.
+// Remote commands may add or delete items while the editor is open, so the
+// item is looked up again, and the edit is dropped if the item is gone.
+func (app *App) replaceContextItem(item domain.ContextItem) func(app *App, v string) {
+	context := app.session.Context()
+
+	return func(app *App, v string) {
+		index := context.Index(item)
+		if context != app.session.Context() || index < 0 {
+			app.notify(levelWarning, "The edited item was removed, the edit is dropped")
+			return
+		}
+
+		context.ReplaceItem(index, domain.TextItem(v))
+		app.main.contextItemsList.SetItemsFromSessionContextItems(context.Items())
+	}
+}
+
 func runAgent(m *App) tea.Cmd {
-	return func() tea.Msg {
-		err := m.agent.Run(m.session)
+	m.setRunning(true)
+	m.runErr = nil
+
+	m.runs++
+	m.run = m.runs
+	m.notify(levelInfo, fmt.Sprintf("Run %d started", m.run))
+
+	// the command runs in another goroutine, it mustn't read m or the
+	// session
+	agent, run, messages := m.agent, m.run, convertSessionToMessages(m.session)
+
+	return tea.Batch(m.status.start(), func() tea.Msg {
+		err := agent.Run(run, messages)
 		if err != nil {
-			return ErrMsg{err}
+			return streamMsg{run: run, msg: ErrMsg{err}}
 		}
 
 		return nil
-	}
+	})
 }
 
 func processEvents(events chan tea.Msg) tea.Cmd {
@@ -259,7 +641,8 @@ func (m *App) handleWindowSize(width, height int) {
 	m.width = width
 	m.height = height
 
-	m.main.SetSize(width, height)
+	m.main.SetSize(width, height-1) // the status bar takes the last line
+	m.status.SetSize(width, 1)
 	m.setDialogSize()
 
 	if !m.uiReady {
@@ -269,7 +652,7 @@ func (m *App) handleWindowSize(width, height int) {
 
 func (m *App) setDialogSize() {
 	if m.dialog != nil {
-		m.dialog.SetSize(m.width/2, 3) // TODO: this height is ignored
+		m.dialog.SetSize(m.width/2, m.height-2) // dialogs size their height to their content
 	}
 }
 
@@ -282,5 +665,40 @@ func (m *App) addContextItem(item domain.ContextItem) {
 }
 
 func (m *App) handleError(err error) {
-	m.showDialog(NewErrorDialog(err))
+	m.logger.Error("Error", slog.String("error", err.Error()))
+	m.notify(levelError, err.Error())
+	m.showDialog(NewErrorDialog(err, m.keys.Error))
+}
+
+// showAPIKeyDialog asks for the API key used by the agent for the rest of
+// the session.
+func (m *App) showAPIKeyDialog() {
+	dialog := NewInputDialog(func(app *App, v string) error {
+		key := strings.TrimSpace(v)
+		if key == "" {
+			return nil
+		}
+		app.agent.SetAPIKey(key)
+		return nil
+	})
+	dialog.SetTitle("API key")
+	dialog.input.EchoMode = textinput.EchoPassword
+	m.showDialog(dialog)
+}
+
+// deleteLargestContextItem removes the context item taking the most room in
+// the request.
+func (m *App) deleteLargestContextItem() {
+	items := m.session.Context().Items()
+	if len(items) == 0 {
+		return
+	}
+
+	largest := 0
+	for i, item := range items {
+		if len(item.Message()) > len(items[largest].Message()) {
+			largest = i
+		}
+	}
+	m.deleteContextItem(largest)
 }
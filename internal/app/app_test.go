@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"testing"
+	"time"
 
 	tea "github.com/charmbracelet/bubbletea/v2"
 	"github.com/gkampitakis/go-snaps/snaps"
@@ -14,6 +15,8 @@ import (
 func makeApp(t *testing.T, cwd string) App {
 	app, err := MakeApp(cwd, make(chan tea.Msg))
 	require.Nil(t, err)
+	app.status.now = func() time.Time { return testTime } // stable durations in snapshots
+	app.notifications.now = app.status.now
 	return app
 }
 
@@ -38,7 +41,12 @@ func render(t *testing.T, model tea.Model) string {
 	return v
 }
 
-func key(code rune) tea.Msg {
+// chunkMsg returns the message of a frame with a single chunk.
+func chunkMsg(text string) streamChunkReceived {
+	return streamChunkReceived{text: text, chunks: 1}
+}
+
+func keyPress(code rune) tea.Msg {
 	return tea.KeyPressMsg{Code: code, Text: string(code)}
 }
 
@@ -67,11 +75,11 @@ func TestApp(t *testing.T) {
 
 		app := bareApp(t)
 		model, _ := app.Update(streamStarted{})
-		model, _ = model.Update(streamChunkReceived("1\n\n2\n\n3\n\n4\n\n5\n\n6"))
+		model, _ = model.Update(chunkMsg("1\n\n2\n\n3\n\n4\n\n5\n\n6"))
 		v := render(t, model)
 		snaps.MatchStandaloneSnapshot(t, v)
 
-		model, _ = model.Update(streamChunkReceived("\n\n7\n\n8\n\n"))
+		model, _ = model.Update(chunkMsg("\n\n7\n\n8\n\n"))
 		v = render(t, model)
 		snaps.MatchStandaloneSnapshot(t, v)
 	})
@@ -81,7 +89,7 @@ func TestApp(t *testing.T) {
 
 		app := bareApp(t)
 		model, _ := app.Update(streamStarted{})
-		model, _ = model.Update(streamChunkReceived("1\n\n2\n\n3\n\n4\n\n5\n\n6\n\n7\n\n8"))
+		model, _ = model.Update(chunkMsg("1\n\n2\n\n3\n\n4\n\n5\n\n6\n\n7\n\n8"))
 		v := render(t, model)
 		snaps.MatchStandaloneSnapshot(t, v)
 
@@ -125,7 +133,16 @@ func TestApp(t *testing.T) {
 		t.Run("add-context-item-file", func(t *testing.T) {
 			app := bareApp(t)
 
-			model, cmd := app.Update(AddContextItemFileMsg("test.txt"))
+			model, cmd := app.Update(AddContextItemFileMsg("app_test.go"))
+			assert.Nil(t, cmd)
+			v := render(t, model)
+			snaps.MatchStandaloneSnapshot(t, v)
+		})
+
+		t.Run("add-context-item-file when file does not exist", func(t *testing.T) {
+			app := bareApp(t)
+
+			model, cmd := app.Update(AddContextItemFileMsg("nonexistent.txt"))
 			assert.Nil(t, cmd)
 			v := render(t, model)
 			snaps.MatchStandaloneSnapshot(t, v)
@@ -142,7 +159,7 @@ func TestApp(t *testing.T) {
 
 		t.Run("new-session", func(t *testing.T) {
 			app := bareApp(t)
-			model, cmd := app.Update(AddContextItemFileMsg("test.txt"))
+			model, cmd := app.Update(AddContextItemFileMsg("app_test.go"))
 
 			model, cmd = model.Update(NewSessionMsg{})
 			assert.Nil(t, cmd)
@@ -150,6 +167,64 @@ func TestApp(t *testing.T) {
 			snaps.MatchStandaloneSnapshot(t, v)
 		})
 
+		t.Run("reply query", func(t *testing.T) {
+			app := bareApp(t)
+			app = update(app, streamFinished("first reply"))
+
+			query := NewReplyQueryMsg()
+			app = update(app, query)
+			assert.Equal(t, Result{Output: "first reply"}, <-query.Result())
+
+			// queries wait for the run in progress
+			app = update(app, RunMsg{})
+			query = NewReplyQueryMsg()
+			app = update(app, query)
+			assert.Empty(t, query.Result())
+
+			app = update(app, streamStarted{})
+			app = update(app, chunkMsg("second"))
+			assert.Empty(t, query.Result())
+
+			// errors that aren't of the run don't end it
+			app = update(app, ErrMsg{Err: fmt.Errorf("editor failed")})
+			assert.True(t, app.running)
+			assert.Empty(t, query.Result())
+
+			app = update(app, streamFinished("second reply"))
+			assert.Equal(t, Result{Output: "second reply"}, <-query.Result())
+		})
+
+		t.Run("reply query after a failed run", func(t *testing.T) {
+			app := bareApp(t)
+			app = update(app, RunMsg{})
+			query := NewReplyQueryMsg()
+			app = update(app, query)
+
+			err := fmt.Errorf("provider failed")
+			app = update(app, streamMsg{run: app.run, msg: chunkMsg("partial")})
+			app = update(app, streamMsg{run: app.run, msg: ErrMsg{Err: err}})
+			assert.False(t, app.running)
+			assert.Equal(t, Result{Output: "partial", Err: err}, <-query.Result())
+
+			// the error is reported until the next run
+			query = NewReplyQueryMsg()
+			app = update(app, query)
+			assert.Equal(t, err, (<-query.Result()).Err)
+		})
+
+		t.Run("remote commands report their errors", func(t *testing.T) {
+			app := bareApp(t)
+
+			command := NewCommandMsg(AddContextItemFileMsg("app_test.go"))
+			app = update(app, eventMsg{command})
+			assert.Equal(t, Result{}, <-command.Result())
+
+			command = NewCommandMsg(AddContextItemFileMsg("nonexistent.txt"))
+			app = update(app, eventMsg{command})
+			assert.Error(t, (<-command.Result()).Err)
+			assert.Len(t, app.session.Context().Items(), 1)
+		})
+
 		t.Run("ErrMsg", func(t *testing.T) {
 			app := bareApp(t)
 
@@ -165,14 +240,14 @@ func TestApp(t *testing.T) {
 	t.Run("input", func(t *testing.T) {
 		app := bareApp(t)
 
-		app = update(app, key('h'))
-		app = update(app, key('e'))
-		app = update(app, key('y'))
+		app = update(app, keyPress('h'))
+		app = update(app, keyPress('e'))
+		app = update(app, keyPress('y'))
 
 		v := app.View()
 		snaps.MatchSnapshot(t, v)
 
-		app = update(app, key(tea.KeyEnter))
+		app = update(app, keyPress(tea.KeyEnter))
 		v = app.View()
 		snaps.MatchSnapshot(t, v)
 	})
@@ -0,0 +1,99 @@
+package app
+
+import (
+	"strings"
+
+	"mark/internal/util"
+
+	"github.com/charmbracelet/bubbles/v2/key"
+	tea "github.com/charmbracelet/bubbletea/v2"
+	"github.com/charmbracelet/lipgloss/v2"
+	"github.com/charmbracelet/x/ansi"
+)
+
+// choicePickerRows is the maximum number of entries shown at once.
+const choicePickerRows = 10
+
+// ChoicePicker is a dialog to choose one of a list of entries. Entries are
+// shown as a label, the labels aligned, followed by a description.
+type ChoicePicker[T any] struct {
+	width   int
+	title   string
+	entries []T
+	list    scrollList
+	keys    ChoiceKeys
+	// columns returns the label and the description of the entry at index.
+	columns  func(index int, entry T) (label, description string)
+	callback func(app *App, entry T) tea.Cmd
+}
+
+func newChoicePicker[T any](title string, entries []T, keys ChoiceKeys, columns func(int, T) (string, string), callback func(app *App, entry T) tea.Cmd) *ChoicePicker[T] {
+	p := &ChoicePicker[T]{
+		title:    title,
+		entries:  entries,
+		keys:     keys,
+		columns:  columns,
+		callback: callback,
+	}
+	p.list.setRows(choicePickerRows)
+	p.list.reset(len(entries))
+
+	return p
+}
+
+func (p *ChoicePicker[T]) Focus() {}
+
+func (p *ChoicePicker[T]) Blur() {}
+
+func (p *ChoicePicker[T]) SetSize(width, height int) {
+	p.width = width
+	p.list.setRows(util.Clamp(height-2, 1, choicePickerRows)) // borders
+}
+
+func (p *ChoicePicker[T]) Update(app *App, msg tea.Msg) tea.Cmd {
+	switch msg := msg.(type) {
+	case tea.KeyPressMsg:
+		switch {
+		case key.Matches(msg, p.keys.Close):
+			app.hideDialog()
+		case key.Matches(msg, p.keys.Choose):
+			app.hideDialog()
+			if p.list.cursor < len(p.entries) {
+				return p.callback(app, p.entries[p.list.cursor])
+			}
+		case key.Matches(msg, p.keys.Up):
+			p.list.moveCursor(-1)
+		case key.Matches(msg, p.keys.Down):
+			p.list.moveCursor(1)
+		}
+	}
+
+	return nil
+}
+
+func (p *ChoicePicker[T]) View() string {
+	width := max(p.width-2, 0) // borders
+
+	labels := make([]string, len(p.entries))
+	descriptions := make([]string, len(p.entries))
+	labelWidth := 0
+	for i, entry := range p.entries {
+		labels[i], descriptions[i] = p.columns(i, entry)
+		labelWidth = max(labelWidth, ansi.StringWidth(labels[i]))
+	}
+
+	var lines []string
+	for i := p.list.offset; i < p.list.end(); i++ {
+		line := labels[i] + strings.Repeat(" ", labelWidth-ansi.StringWidth(labels[i])) + "  " + descriptions[i]
+		line = ansi.Truncate(line, width, "…")
+
+		if i == p.list.cursor {
+			line = highlightedEntryStyle.Width(width).Render(ansi.Strip(line))
+		}
+		lines = append(lines, line)
+	}
+
+	content := lipgloss.NewStyle().Width(width).Render(strings.Join(lines, "\n"))
+
+	return util.RenderBorderWithTitle(content, focusedBorderStyle, p.title, focusedPanelTitleStyle)
+}
@@ -0,0 +1,23 @@
+package app
+
+import (
+	"os"
+
+	tea "github.com/charmbracelet/bubbletea/v2"
+	"github.com/charmbracelet/x/ansi"
+)
+
+// copyToClipboard sets the system clipboard with an OSC 52 escape sequence,
+// which the terminal handles even over SSH.
+//
+// tmux only forwards the sequence to the outer terminal with set-clipboard
+// on, so it's also sent wrapped in a passthrough sequence, which works with
+// allow-passthrough on instead.
+func copyToClipboard(text string) tea.Cmd {
+	if os.Getenv("TMUX") == "" {
+		return tea.SetClipboard(text)
+	}
+
+	seq := ansi.SetSystemClipboard(text)
+	return tea.Raw(seq + ansi.TmuxPassthrough(seq))
+}
@@ -0,0 +1,69 @@
+package app
+
+import (
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea/v2"
+	"github.com/gkampitakis/go-snaps/snaps"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const replyWithCode = "Two blocks:\n\n```go\npackage main\n```\n\n```sh\ngo run .\n```\n"
+
+func TestCopyToClipboard(t *testing.T) {
+	t.Run("uses OSC 52", func(t *testing.T) {
+		t.Setenv("TMUX", "")
+
+		assert.Equal(t, tea.SetClipboard("text")(), copyToClipboard("text")())
+	})
+
+	t.Run("passes through tmux", func(t *testing.T) {
+		t.Setenv("TMUX", "/tmp/tmux-1000/default,1,0")
+
+		msg, ok := copyToClipboard("text")().(tea.RawMsg)
+		require.True(t, ok)
+
+		seq := msg.Msg.(string)
+		assert.True(t, strings.HasPrefix(seq, "\x1b]52;c;dGV4dA==\x07"))
+		assert.Contains(t, seq, "\x1bPtmux;")
+	})
+}
+
+func TestCopy(t *testing.T) {
+	t.Setenv("TMUX", "")
+
+	t.Run("reply", func(t *testing.T) {
+		app := bareApp(t)
+		_, cmd := app.Update(keyPress('y'))
+		assert.Nil(t, cmd)
+
+		app = update(app, streamFinished(replyWithCode))
+		_, cmd = app.Update(keyPress('y'))
+		assert.Equal(t, tea.SetClipboard(replyWithCode)(), cmd())
+	})
+
+	t.Run("single code block", func(t *testing.T) {
+		app := bareApp(t)
+		app = update(app, streamFinished("```go\npackage main\n```\n"))
+
+		model, cmd := app.Update(keyPress('Y'))
+		assert.Nil(t, model.(App).dialog)
+		assert.Equal(t, tea.SetClipboard("package main\n")(), cmd())
+	})
+
+	t.Run("code block picker", func(t *testing.T) {
+		app := bareApp(t)
+		app = update(app, streamFinished(replyWithCode))
+
+		app = update(app, keyPress('Y'))
+		require.IsType(t, &CodeBlockPicker{}, app.dialog)
+		snaps.MatchStandaloneSnapshot(t, app.View())
+
+		app = update(app, keyPress('j'))
+		model, cmd := app.Update(keyPress(tea.KeyEnter))
+		assert.Nil(t, model.(App).dialog)
+		assert.Equal(t, tea.SetClipboard("go run .\n")(), cmd())
+	})
+}
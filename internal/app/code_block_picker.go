@@ -0,0 +1,28 @@
+package app
+
+import (
+	"fmt"
+
+	"mark/internal/markdown"
+
+	tea "github.com/charmbracelet/bubbletea/v2"
+)
+
+// CodeBlockPicker is a dialog to choose one of the code blocks of the reply.
+// Blocks are listed by language and first line.
+type CodeBlockPicker = ChoicePicker[markdown.CodeBlock]
+
+func NewCodeBlockPicker(title string, blocks []markdown.CodeBlock, keys ChoiceKeys, callback func(app *App, block markdown.CodeBlock) tea.Cmd) *CodeBlockPicker {
+	columns := func(i int, block markdown.CodeBlock) (string, string) {
+		return fmt.Sprintf("%d. %s", i+1, blockLanguage(block)), block.FirstLine()
+	}
+	return newChoicePicker(title, blocks, keys, columns, callback)
+}
+
+// blockLanguage returns the language shown for block.
+func blockLanguage(block markdown.CodeBlock) string {
+	if block.Language == "" {
+		return "text"
+	}
+	return block.Language
+}
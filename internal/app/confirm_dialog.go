@@ -0,0 +1,89 @@
+package app
+
+import (
+	"fmt"
+	"strings"
+
+	"mark/internal/util"
+
+	"github.com/charmbracelet/bubbles/v2/key"
+	tea "github.com/charmbracelet/bubbletea/v2"
+	"github.com/charmbracelet/lipgloss/v2"
+	"github.com/charmbracelet/x/ansi"
+)
+
+// confirmRows is the maximum number of content lines shown at once.
+const confirmRows = 15
+
+// ConfirmDialog shows content, like a diff, and asks to confirm an action.
+type ConfirmDialog struct {
+	width     int
+	rows      int // number of content lines shown at once
+	title     string
+	lines     []string
+	offset    int // index of the first visible line
+	keys      ConfirmKeys
+	onConfirm func(app *App) tea.Cmd
+}
+
+func NewConfirmDialog(title string, content string, keys ConfirmKeys, onConfirm func(app *App) tea.Cmd) *ConfirmDialog {
+	return &ConfirmDialog{
+		rows:      confirmRows,
+		title:     title,
+		lines:     strings.Split(strings.TrimSuffix(content, "\n"), "\n"),
+		keys:      keys,
+		onConfirm: onConfirm,
+	}
+}
+
+func (dialog *ConfirmDialog) Focus() {}
+
+func (dialog *ConfirmDialog) Blur() {}
+
+func (dialog *ConfirmDialog) SetSize(width, height int) {
+	dialog.width = width
+	dialog.rows = util.Clamp(height-3, 1, confirmRows) // borders and status
+	dialog.offset = util.Clamp(dialog.offset, 0, max(len(dialog.lines)-dialog.rows, 0))
+}
+
+func (dialog *ConfirmDialog) Update(app *App, msg tea.Msg) tea.Cmd {
+	switch msg := msg.(type) {
+	case tea.KeyPressMsg:
+		keys := dialog.keys
+		maxOffset := max(len(dialog.lines)-dialog.rows, 0)
+
+		switch {
+		case key.Matches(msg, keys.Yes):
+			app.hideDialog()
+			return dialog.onConfirm(app)
+		case key.Matches(msg, keys.No):
+			app.hideDialog()
+		case key.Matches(msg, keys.Up):
+			dialog.offset = util.Clamp(dialog.offset-1, 0, maxOffset)
+		case key.Matches(msg, keys.Down):
+			dialog.offset = util.Clamp(dialog.offset+1, 0, maxOffset)
+		}
+	}
+
+	return nil
+}
+
+func (dialog *ConfirmDialog) View() string {
+	width := max(dialog.width-2, 0) // borders
+
+	var lines []string
+	end := min(dialog.offset+dialog.rows, len(dialog.lines))
+	for _, line := range dialog.lines[dialog.offset:end] {
+		lines = append(lines, ansi.Truncate(line, width, "…"))
+	}
+
+	status := keyHints(dialog.keys.Yes, dialog.keys.No)
+	if len(dialog.lines) > dialog.rows {
+		status = fmt.Sprintf("%d-%d of %d · ", dialog.offset+1, end, len(dialog.lines)) + status
+	}
+	lines = append(lines, hintStyle.Render(ansi.Truncate(status, width, "…")))
+
+	content := lipgloss.NewStyle().Width(width).Render(strings.Join(lines, "\n"))
+
+	return util.RenderBorderWithTitle(content, focusedBorderStyle, dialog.title, focusedPanelTitleStyle)
+}
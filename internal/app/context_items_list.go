@@ -7,6 +7,7 @@ import (
 
 	"mark/internal/domain"
 
+	"github.com/charmbracelet/bubbles/v2/key"
 	"github.com/charmbracelet/bubbles/v2/list"
 	tea "github.com/charmbracelet/bubbletea/v2"
 	"github.com/charmbracelet/lipgloss/v2"
@@ -88,6 +89,19 @@ func (l *ContextItemsList) SetSize(width, height int) {
 	l.model.SetHeight(height)
 }
 
+// SetKeyMap replaces the bindings of the list, so only the configured keys
+// move through the items.
+func (l *ContextItemsList) SetKeyMap(keys ContextKeys) {
+	l.model.KeyMap = list.KeyMap{
+		CursorUp:   keys.Up,
+		CursorDown: keys.Down,
+		PrevPage:   keys.PageUp,
+		NextPage:   keys.PageDown,
+		GoToStart:  keys.Top,
+		GoToEnd:    keys.Bottom,
+	}
+}
+
 func (l *ContextItemsList) Width() int {
 	return l.model.Width()
 }
@@ -98,16 +112,31 @@ func (l *ContextItemsList) Update(app *App, msg tea.Msg) tea.Cmd {
 
 	switch msg := msg.(type) {
 	case tea.KeyPressMsg:
-		switch msg.String() {
-		case "f":
+		keys := app.keys.Context
+
+		switch {
+		case key.Matches(msg, keys.AddFile):
 			inputHandled = true
-			app.showAddContextFileDialog()
-		case "n":
+			cmds = append(cmds, app.indexFiles())
+		case key.Matches(msg, keys.AddText):
 			inputHandled = true
 			app.showAddContextDialog()
-		case "d":
+		case key.Matches(msg, keys.Delete):
 			inputHandled = true
 			app.deleteContextItem(l.model.GlobalIndex())
+		case key.Matches(msg, keys.Edit):
+			inputHandled = true
+			cmds = append(cmds, app.editContextItem(l.model.GlobalIndex()))
+		case key.Matches(msg, keys.HalfPageUp):
+			inputHandled = true
+			for range l.model.Height() / 2 {
+				l.model.CursorUp()
+			}
+		case key.Matches(msg, keys.HalfPageDown):
+			inputHandled = true
+			for range l.model.Height() / 2 {
+				l.model.CursorDown()
+			}
 		}
 	}
 
@@ -0,0 +1,104 @@
+package app
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea/v2"
+)
+
+// editorFinishedMsg is sent when the editor opened by openEditor exits.
+type editorFinishedMsg struct {
+	path  string
+	temp  bool                     // remove the file after reading it
+	apply func(app *App, v string) // receives the edited content
+	err   error
+}
+
+// editorCommand returns the command for the user's editor, from $VISUAL or
+// $EDITOR, which may include arguments.
+func editorCommand(path string) (*exec.Cmd, error) {
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	if editor == "" {
+		editor = "vi"
+	}
+
+	args := strings.Fields(editor)
+	if len(args) == 0 {
+		return nil, fmt.Errorf("invalid editor: %q", editor)
+	}
+
+	return exec.Command(args[0], append(args[1:], path)...), nil
+}
+
+// editText opens content in the editor. The TUI is suspended until the
+// editor exits, then apply receives the edited content.
+func editText(content string, apply func(app *App, v string)) tea.Cmd {
+	file, err := os.CreateTemp("", "mark-*.md")
+	if err != nil {
+		return errCmd(fmt.Errorf("failed to create file for the editor: %w", err))
+	}
+	defer file.Close()
+
+	if _, err := file.WriteString(content); err != nil {
+		os.Remove(file.Name())
+		return errCmd(fmt.Errorf("failed to write file for the editor: %w", err))
+	}
+
+	return openEditor(file.Name(), true, apply)
+}
+
+// editFile opens a file in the editor.
+func editFile(path string) tea.Cmd {
+	return openEditor(path, false, nil)
+}
+
+func openEditor(path string, temp bool, apply func(app *App, v string)) tea.Cmd {
+	cmd, err := editorCommand(path)
+	if err != nil {
+		if temp {
+			os.Remove(path)
+		}
+		return errCmd(err)
+	}
+
+	return tea.ExecProcess(cmd, func(err error) tea.Msg {
+		return editorFinishedMsg{path: path, temp: temp, apply: apply, err: err}
+	})
+}
+
+// handleEditorFinished reads the result of the editor and applies it.
+func (m *App) handleEditorFinished(msg editorFinishedMsg) {
+	if msg.temp {
+		defer os.Remove(msg.path)
+	}
+
+	if msg.err != nil {
+		m.handleError(fmt.Errorf("editor failed: %w", msg.err))
+		return
+	}
+
+	if msg.apply == nil {
+		return
+	}
+
+	content, err := os.ReadFile(msg.path)
+	if err != nil {
+		m.handleError(fmt.Errorf("failed to read file from the editor: %w", err))
+		return
+	}
+
+	// editors usually add a newline at the end of the file
+	msg.apply(m, strings.TrimSuffix(string(content), "\n"))
+}
+
+func errCmd(err error) tea.Cmd {
+	return func() tea.Msg {
+		return ErrMsg{Err: err}
+	}
+}
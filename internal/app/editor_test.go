@@ -0,0 +1,110 @@
+package app
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"mark/internal/domain"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEditor(t *testing.T) {
+	t.Run("editorCommand", func(t *testing.T) {
+		t.Setenv("VISUAL", "")
+		t.Setenv("EDITOR", "code --wait")
+
+		cmd, err := editorCommand("file.md")
+		require.NoError(t, err)
+		assert.Equal(t, []string{"code", "--wait", "file.md"}, cmd.Args)
+
+		t.Setenv("VISUAL", "nvim")
+
+		cmd, err = editorCommand("file.md")
+		require.NoError(t, err)
+		assert.Equal(t, []string{"nvim", "file.md"}, cmd.Args)
+	})
+
+	t.Run("editContextItem", func(t *testing.T) {
+		t.Setenv("TMPDIR", t.TempDir()) // the file for the editor is created on open
+
+		app := bareApp(t)
+		app = update(app, AddContextItemTextMsg("some text"))
+
+		assert.NotNil(t, app.editContextItem(0))
+		assert.Nil(t, app.editContextItem(1))
+	})
+
+	t.Run("editor finished", func(t *testing.T) {
+		app := bareApp(t)
+		app = update(app, AddContextItemTextMsg("first"))
+		app = update(app, AddContextItemTextMsg("second"))
+
+		path := filepath.Join(t.TempDir(), "edit.md")
+		require.NoError(t, os.WriteFile(path, []byte("edited\ntext\n"), 0o600))
+
+		app = update(app, editorFinishedMsg{
+			path: path,
+			temp: true,
+			apply: func(app *App, v string) {
+				app.session.Context().ReplaceItem(0, domain.TextItem(v))
+			},
+		})
+
+		items := app.session.Context().Items()
+		assert.Equal(t, "edited\ntext", items[0].Message())
+		assert.Equal(t, "second", items[1].Message())
+		assert.NoFileExists(t, path)
+	})
+
+	t.Run("items change while the editor is open", func(t *testing.T) {
+		app := bareApp(t)
+		app = update(app, AddContextItemTextMsg("first"))
+		app = update(app, AddContextItemTextMsg("second"))
+
+		replace := app.replaceContextItem(app.session.Context().Items()[1])
+		app.deleteContextItem(0)
+		app = update(app, AddContextItemTextMsg("third"))
+
+		replace(&app, "edited")
+		assert.Equal(t, []string{"edited", "third"}, itemMessages(app))
+
+		// the edit of a deleted item is dropped
+		replace = app.replaceContextItem(app.session.Context().Items()[0])
+		app.deleteContextItem(0)
+		replace(&app, "lost")
+		assert.Equal(t, []string{"third"}, itemMessages(app))
+
+		replace = app.replaceContextItem(app.session.Context().Items()[0])
+		app = update(app, NewSessionMsg{})
+		app = update(app, AddContextItemTextMsg("third"))
+		replace(&app, "lost")
+		assert.Equal(t, []string{"third"}, itemMessages(app))
+	})
+
+	t.Run("editor failed", func(t *testing.T) {
+		app := bareApp(t)
+
+		app = update(app, editorFinishedMsg{
+			path: filepath.Join(t.TempDir(), "edit.md"),
+			err:  errors.New("exit status 1"),
+			apply: func(app *App, v string) {
+				t.Fatal("should not apply the result of a failed editor")
+			},
+		})
+
+		assert.IsType(t, &ErrorDialog{}, app.dialog)
+	})
+}
+
+// itemMessages returns the messages of the context items of app.
+func itemMessages(app App) []string {
+	var messages []string
+	for _, item := range app.session.Context().Items() {
+		messages = append(messages, item.Message())
+	}
+	return messages
+}
@@ -1,28 +1,68 @@
 package app
 
 import (
-	"log/slog"
+	"strings"
 
 	"mark/internal/util"
 
-	"github.com/charmbracelet/bubbles/v2/viewport"
+	"github.com/charmbracelet/bubbles/v2/key"
 	tea "github.com/charmbracelet/bubbletea/v2"
 	"github.com/charmbracelet/lipgloss/v2"
+	"github.com/charmbracelet/x/ansi"
 )
 
+// errorAction is a way out of an error, suggested by the error dialog.
+type errorAction struct {
+	binding key.Binding
+	run     func(app *App) tea.Cmd
+}
+
+// ErrorDialog explains an error and suggests actions depending on its kind,
+// like entering an API key. Keys not bound to an action close it.
 type ErrorDialog struct {
 	width    int
 	height   int
 	hasFocus bool
-	viewport viewport.Model
+	err      error
+	help     errorHelp
+	actions  []errorAction
+	close    key.Binding
 }
 
-func NewErrorDialog(err error) *ErrorDialog {
-	viewport := viewport.New()
-	viewport.SetContent(err.Error())
+func NewErrorDialog(err error, keys ErrorKeys) *ErrorDialog {
+	kind := classifyError(err)
 
 	return &ErrorDialog{
-		viewport: viewport,
+		err:     err,
+		help:    errorHelps[kind],
+		actions: errorActions(kind, keys),
+		close:   keys.Close,
+	}
+}
+
+// errorActions returns the actions suggested for a kind of error.
+func errorActions(kind errorKind, keys ErrorKeys) []errorAction {
+	retry := errorAction{keys.Retry, func(app *App) tea.Cmd { return runAgent(app) }}
+	setAPIKey := errorAction{keys.SetAPIKey, func(app *App) tea.Cmd {
+		app.showAPIKeyDialog()
+		return nil
+	}}
+	trimContext := errorAction{keys.TrimContext, func(app *App) tea.Cmd {
+		app.deleteLargestContextItem()
+		return nil
+	}}
+
+	switch kind {
+	case errorMissingAPIKey:
+		return []errorAction{setAPIKey}
+	case errorAuth:
+		return []errorAction{setAPIKey, retry}
+	case errorRateLimit, errorUnavailable, errorNetwork:
+		return []errorAction{retry}
+	case errorContextTooLong:
+		return []errorAction{trimContext, retry}
+	default:
+		return nil
 	}
 }
 
@@ -37,32 +77,58 @@ func (dialog *ErrorDialog) Blur() {
 func (dialog *ErrorDialog) SetSize(width, height int) {
 	dialog.width = width
 	dialog.height = height
-	dialog.viewport.SetWidth(width - 2)   // Subtract 2 for borders
-	dialog.viewport.SetHeight(height - 2) // Subtract 2 for borders
 }
 
 func (dialog *ErrorDialog) Update(app *App, msg tea.Msg) tea.Cmd {
-	var cmds []tea.Cmd
-
 	switch msg := msg.(type) {
 	case tea.KeyPressMsg:
-		switch msg.String() {
-		default:
-			app.hideDialog()
+		app.hideDialog()
+
+		for _, action := range dialog.actions {
+			if key.Matches(msg, action.binding) {
+				return action.run(app)
+			}
 		}
 	}
 
-	return tea.Batch(cmds...)
+	return nil
 }
 
+// View sizes the dialog to its content, up to the height it was given. The
+// details of the error are cut first, then the explanation.
 func (dialog *ErrorDialog) View() string {
-	slog.Info("Rendering error dialog", "width", dialog.width, "height", dialog.height)
-	return util.RenderBorderWithTitle(
-		dialog.viewport.View(),
-		dialog.BorderStyle(),
-		"Error",
-		dialog.TitleStyle(),
-	)
+	width := max(dialog.width-2, 1) // borders
+
+	text := strings.Split(ansi.Wordwrap(dialog.help.text, width, ""), "\n")
+	details := strings.Split(ansi.Wrap(dialog.err.Error(), width, ""), "\n")
+	for i, line := range details {
+		details[i] = hintStyle.Render(line)
+	}
+	footer := strings.Split(ansi.Wordwrap(dialog.footer(), width, ""), "\n")
+	for i, line := range footer {
+		footer[i] = hintStyle.Render(line)
+	}
+
+	rows := max(dialog.height-2, 1) // borders
+	var lines []string
+	if len(text)+len(details)+len(footer)+2 <= rows {
+		lines = append(append(append(append(text, ""), details...), ""), footer...)
+	} else {
+		lines = append(text[:min(len(text), max(rows-len(footer), 0))], footer...)
+	}
+
+	content := lipgloss.NewStyle().Width(width).Render(strings.Join(lines, "\n"))
+
+	return util.RenderBorderWithTitle(content, dialog.BorderStyle(), dialog.help.title, dialog.TitleStyle())
+}
+
+// footer lists the keys of the actions and the key closing the dialog.
+func (dialog *ErrorDialog) footer() string {
+	var bindings []key.Binding
+	for _, action := range dialog.actions {
+		bindings = append(bindings, action.binding)
+	}
+	return keyHints(append(bindings, dialog.close)...)
 }
 
 func (dialog *ErrorDialog) BorderStyle() lipgloss.Style {
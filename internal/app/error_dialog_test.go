@@ -0,0 +1,141 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+
+	"mark/internal/domain"
+	"mark/internal/llm/provider"
+
+	tea "github.com/charmbracelet/bubbletea/v2"
+	"github.com/gkampitakis/go-snaps/snaps"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClassifyError(t *testing.T) {
+	t.Parallel()
+
+	apiError := func(status int, class error) error {
+		return &provider.RetryError{Attempts: 4, Err: &provider.APIError{StatusCode: status, Class: class, Err: assert.AnError}}
+	}
+
+	tests := []struct {
+		name string
+		err  error
+		want errorKind
+	}{
+		{"missing API key", apiError(http.StatusUnauthorized, provider.ErrMissingAPIKey), errorMissingAPIKey},
+		{"invalid API key", apiError(http.StatusUnauthorized, nil), errorAuth},
+		{"forbidden", apiError(http.StatusForbidden, nil), errorAuth},
+		{"rate limited", apiError(http.StatusTooManyRequests, nil), errorRateLimit},
+		{"server error", apiError(http.StatusBadGateway, nil), errorUnavailable},
+		{"context too long", apiError(http.StatusBadRequest, provider.ErrContextTooLong), errorContextTooLong},
+		{"network", &net.OpError{Op: "dial", Net: "tcp", Err: assert.AnError}, errorNetwork},
+		{"socket", fmt.Errorf("listen: %w", &net.OpError{Op: "listen", Net: "unix", Err: assert.AnError}), errorSocket},
+		{"missing file", fmt.Errorf("notes.txt: %w", fs.ErrNotExist), errorFileAccess},
+		{"unreadable file", &fs.PathError{Op: "open", Path: "notes.txt", Err: os.ErrPermission}, errorFileAccess},
+		{"other", errors.New("boom"), errorOther},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, tt.want, classifyError(tt.err))
+		})
+	}
+}
+
+func TestErrorDialog(t *testing.T) {
+	t.Parallel()
+
+	errorApp := func(t *testing.T, err error) App {
+		t.Helper()
+
+		app := bareApp(t)
+		app = update(app, ErrMsg{Err: err})
+		require.IsType(t, &ErrorDialog{}, app.dialog)
+		return app
+	}
+
+	t.Run("explains the error and suggests actions", func(t *testing.T) {
+		t.Parallel()
+
+		app := errorApp(t, &provider.APIError{StatusCode: http.StatusBadRequest, Class: provider.ErrContextTooLong, Err: errors.New("maximum context length exceeded")})
+
+		snaps.MatchStandaloneSnapshot(t, render(t, app))
+	})
+
+	t.Run("is cut to the height of the screen", func(t *testing.T) {
+		t.Parallel()
+
+		app := errorApp(t, errors.New(strings.Repeat("a very long error message ", 40)))
+
+		v := render(t, app)
+		assert.Equal(t, 16, strings.Count(v, "\n")+1)
+		assert.Contains(t, v, "esc/enter/q close")
+	})
+
+	t.Run("other keys close it", func(t *testing.T) {
+		t.Parallel()
+
+		app := errorApp(t, &provider.APIError{StatusCode: http.StatusTooManyRequests, Err: assert.AnError})
+		app = update(app, keyPress('x'))
+
+		assert.Nil(t, app.dialog)
+		assert.False(t, app.running)
+	})
+
+	t.Run("retry runs the agent again", func(t *testing.T) {
+		t.Parallel()
+
+		app := errorApp(t, &provider.APIError{StatusCode: http.StatusTooManyRequests, Err: assert.AnError})
+		model, cmd := app.Update(keyPress('r'))
+		app = model.(App)
+
+		assert.Nil(t, app.dialog)
+		assert.True(t, app.running)
+		assert.NotNil(t, cmd)
+	})
+
+	t.Run("enter an API key", func(t *testing.T) {
+		t.Parallel()
+
+		app := errorApp(t, &provider.APIError{StatusCode: http.StatusUnauthorized, Class: provider.ErrMissingAPIKey, Err: assert.AnError})
+		app = update(app, keyPress('k'))
+
+		require.IsType(t, &InputDialog{}, app.dialog)
+		provider := app.agent.provider
+		app = typeText(app, "sk-test")
+		assert.NotContains(t, render(t, app), "sk-test")
+
+		app = update(app, keyPress(tea.KeyEnter))
+		assert.Nil(t, app.dialog)
+		assert.NotSame(t, provider, app.agent.provider)
+	})
+
+	t.Run("remove the largest context item", func(t *testing.T) {
+		t.Parallel()
+
+		app := errorApp(t, &provider.APIError{StatusCode: http.StatusBadRequest, Class: provider.ErrContextTooLong, Err: assert.AnError})
+		app.addContextItem(domain.TextItem("small"))
+		app.addContextItem(domain.TextItem(strings.Repeat("large ", 100)))
+		app.addContextItem(domain.TextItem("smaller"))
+
+		app = update(app, keyPress('d'))
+
+		assert.Nil(t, app.dialog)
+		var texts []string
+		for _, item := range app.session.Context().Items() {
+			texts = append(texts, item.Message())
+		}
+		assert.Equal(t, []string{domain.TextItem("small").Message(), domain.TextItem("smaller").Message()}, texts)
+	})
+}
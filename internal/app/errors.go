@@ -0,0 +1,103 @@
+package app
+
+import (
+	"errors"
+	"io/fs"
+	"net"
+
+	"mark/internal/llm/provider"
+)
+
+// errorKind is a class of errors the error dialog explains.
+type errorKind int
+
+const (
+	errorOther errorKind = iota
+	errorMissingAPIKey
+	errorAuth
+	errorRateLimit
+	errorUnavailable
+	errorNetwork
+	errorContextTooLong
+	errorSocket
+	errorFileAccess
+)
+
+// errorHelp is how the error dialog explains a kind of error.
+type errorHelp struct {
+	title string
+	text  string
+}
+
+var errorHelps = map[errorKind]errorHelp{
+	errorOther: {
+		title: "Error",
+		text:  "Something went wrong.",
+	},
+	errorMissingAPIKey: {
+		title: "Missing API key",
+		text:  "No API key is set. Set OPENAI_API_KEY before starting mark, or enter a key for this session.",
+	},
+	errorAuth: {
+		title: "Authentication failed",
+		text:  "The API key was rejected. Check that it's valid and allowed to use the model.",
+	},
+	errorRateLimit: {
+		title: "Rate limited",
+		text:  "The provider is receiving too many requests, or the quota is used up. Wait a moment before retrying.",
+	},
+	errorUnavailable: {
+		title: "Provider unavailable",
+		text:  "The provider failed to handle the request. It's usually temporary, retry in a moment.",
+	},
+	errorNetwork: {
+		title: "Network error",
+		text:  "The provider couldn't be reached. Check the network connection and retry.",
+	},
+	errorContextTooLong: {
+		title: "Context too long",
+		text:  "The context and prompt don't fit the context window of the model. Remove some context items and retry.",
+	},
+	errorSocket: {
+		title: "Control socket error",
+		text:  "The control socket couldn't be used. Check that its directory is writable and no other mark owns it.",
+	},
+	errorFileAccess: {
+		title: "File access error",
+		text:  "The file couldn't be read. Check that it exists and is readable.",
+	},
+}
+
+// classifyError returns the kind of err. Provider classes are checked
+// first, since their errors may wrap network errors.
+func classifyError(err error) errorKind {
+	switch {
+	case errors.Is(err, provider.ErrMissingAPIKey):
+		return errorMissingAPIKey
+	case errors.Is(err, provider.ErrUnauthorized):
+		return errorAuth
+	case errors.Is(err, provider.ErrRateLimited):
+		return errorRateLimit
+	case errors.Is(err, provider.ErrContextTooLong):
+		return errorContextTooLong
+	case errors.Is(err, provider.ErrUnavailable):
+		return errorUnavailable
+	}
+
+	var opErr *net.OpError
+	if errors.As(err, &opErr) && opErr.Net == "unix" {
+		return errorSocket
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return errorNetwork
+	}
+
+	var pathErr *fs.PathError
+	if errors.Is(err, fs.ErrNotExist) || errors.Is(err, fs.ErrPermission) || errors.As(err, &pathErr) {
+		return errorFileAccess
+	}
+
+	return errorOther
+}
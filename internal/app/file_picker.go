@@ -0,0 +1,309 @@
+package app
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"mark/internal/files"
+	"mark/internal/util"
+
+	"github.com/charmbracelet/bubbles/v2/key"
+	"github.com/charmbracelet/bubbles/v2/textinput"
+	tea "github.com/charmbracelet/bubbletea/v2"
+	"github.com/charmbracelet/lipgloss/v2"
+	"github.com/charmbracelet/x/ansi"
+	"github.com/sahilm/fuzzy"
+)
+
+// filePickerRows is the maximum number of entries shown at once.
+const filePickerRows = 10
+
+var (
+	matchedCharStyle = lipgloss.NewStyle().Foreground(focusColor).Bold(true)
+	hintStyle        = lipgloss.NewStyle().Foreground(lipgloss.Color("8"))
+)
+
+// filePickerEntry is an entry matching the query. Directories end with a
+// slash.
+type filePickerEntry struct {
+	path    string
+	matched []int // byte indexes of the characters matching the query
+}
+
+func (e filePickerEntry) isDir() bool {
+	return strings.HasSuffix(e.path, "/")
+}
+
+// FilePicker is a dialog to search the files of the project. Entries are
+// fuzzy matched against the query. A query ending in a directory, like
+// "internal/app/", only matches entries inside that directory.
+type FilePicker struct {
+	width    int
+	input    textinput.Model
+	files    []string
+	entries  []string // directories and files
+	matches  []filePickerEntry
+	list     scrollList // the visible matches and the cursor
+	selected map[string]bool
+	keys     PickerKeys
+	callback func(paths []string) error
+}
+
+func NewFilePicker(paths []string, keys PickerKeys, callback func(paths []string) error) *FilePicker {
+	input := textinput.New()
+	input.Prompt = "> "
+	input.Placeholder = "Search files"
+	input.Focus()
+
+	picker := &FilePicker{
+		input:    input,
+		files:    paths,
+		entries:  append(files.Directories(paths), paths...),
+		selected: map[string]bool{},
+		keys:     keys,
+		callback: callback,
+	}
+	picker.list.setRows(filePickerRows)
+	picker.filter()
+
+	return picker
+}
+
+func (p *FilePicker) Focus() {
+	p.input.Focus()
+}
+
+func (p *FilePicker) Blur() {
+	p.input.Blur()
+}
+
+func (p *FilePicker) SetSize(width, height int) {
+	p.width = width
+	p.list.setRows(util.Clamp(height-4, 1, filePickerRows)) // borders, query and status
+	p.input.SetWidth(width - 2 - len(p.input.Prompt) - 1)   // borders, prompt and cursor
+}
+
+func (p *FilePicker) Update(app *App, msg tea.Msg) tea.Cmd {
+	switch msg := msg.(type) {
+	case tea.KeyPressMsg:
+		keys := p.keys
+
+		switch {
+		case key.Matches(msg, keys.Close):
+			app.hideDialog()
+			return nil
+		case key.Matches(msg, keys.Add):
+			app.hideDialog()
+			if err := p.callback(p.chosenFiles()); err != nil {
+				app.handleError(err)
+			}
+			return nil
+		case key.Matches(msg, keys.Up):
+			p.list.moveCursor(-1)
+			return nil
+		case key.Matches(msg, keys.Down):
+			p.list.moveCursor(1)
+			return nil
+		case key.Matches(msg, keys.Select):
+			p.toggleSelection()
+			return nil
+		case key.Matches(msg, keys.Complete):
+			p.complete()
+			return nil
+		case key.Matches(msg, keys.Expand):
+			if entry, ok := p.current(); ok && entry.isDir() && p.input.Position() == len(p.input.Value()) {
+				p.setQuery(entry.path)
+				return nil
+			}
+		}
+	}
+
+	var cmd tea.Cmd
+	query := p.input.Value()
+	p.input, cmd = p.input.Update(msg)
+	if p.input.Value() != query {
+		p.filter()
+	}
+
+	return cmd
+}
+
+func (p *FilePicker) View() string {
+	width := max(p.width-2, 0) // borders
+
+	lines := []string{p.input.View()}
+
+	for i := p.list.offset; i < p.list.end(); i++ {
+		lines = append(lines, p.renderEntry(p.matches[i], i == p.list.cursor, width))
+	}
+	for i := p.list.end() - p.list.offset; i < p.list.rows; i++ {
+		lines = append(lines, "")
+	}
+
+	status := fmt.Sprintf("%d/%d", len(p.matches), len(p.entries))
+	if len(p.selected) > 0 {
+		status += fmt.Sprintf(" (%d selected)", len(p.selected))
+	}
+	status += " · " + keyHints(p.keys.Select, p.keys.Complete, p.keys.Add)
+	lines = append(lines, hintStyle.Render(ansi.Truncate(status, width, "…")))
+
+	content := lipgloss.NewStyle().Width(width).Render(strings.Join(lines, "\n"))
+
+	return util.RenderBorderWithTitle(content, focusedBorderStyle, "Add files", focusedPanelTitleStyle)
+}
+
+func (p *FilePicker) renderEntry(entry filePickerEntry, highlighted bool, width int) string {
+	marker := "  "
+	if p.selected[entry.path] {
+		marker = "● "
+	}
+
+	var b strings.Builder
+	for i, r := range entry.path {
+		if slices.Contains(entry.matched, i) {
+			b.WriteString(matchedCharStyle.Render(string(r)))
+		} else {
+			b.WriteRune(r)
+		}
+	}
+
+	line := ansi.Truncate(marker+b.String(), width, "…")
+	if highlighted {
+		return highlightedEntryStyle.Width(width).Render(line)
+	}
+	return line
+}
+
+// filter updates the matches for the current query.
+func (p *FilePicker) filter() {
+	query := p.input.Value()
+
+	// restrict the search to the directory in the query
+	dir := ""
+	pattern := query
+	if i := strings.LastIndex(query, "/"); i >= 0 && slices.Contains(p.entries, query[:i+1]) {
+		dir = query[:i+1]
+		pattern = query[i+1:]
+	}
+
+	var candidates []string
+	for _, entry := range p.entries {
+		if entry != dir && strings.HasPrefix(entry, dir) {
+			candidates = append(candidates, entry[len(dir):])
+		}
+	}
+
+	p.matches = nil
+	if pattern == "" {
+		for _, candidate := range candidates {
+			p.matches = append(p.matches, filePickerEntry{path: dir + candidate})
+		}
+	} else {
+		for _, match := range fuzzy.Find(pattern, candidates) {
+			matched := make([]int, len(match.MatchedIndexes))
+			for i, index := range match.MatchedIndexes {
+				matched[i] = index + len(dir)
+			}
+			p.matches = append(p.matches, filePickerEntry{path: dir + match.Str, matched: matched})
+		}
+	}
+
+	p.list.reset(len(p.matches))
+}
+
+func (p *FilePicker) setQuery(query string) {
+	p.input.SetValue(query)
+	p.input.CursorEnd()
+	p.filter()
+}
+
+func (p *FilePicker) current() (filePickerEntry, bool) {
+	if p.list.cursor >= len(p.matches) {
+		return filePickerEntry{}, false
+	}
+	return p.matches[p.list.cursor], true
+}
+
+// toggleSelection selects or unselects the entry under the cursor, moving
+// to the next one.
+func (p *FilePicker) toggleSelection() {
+	entry, ok := p.current()
+	if !ok {
+		return
+	}
+
+	if p.selected[entry.path] {
+		delete(p.selected, entry.path)
+	} else {
+		p.selected[entry.path] = true
+	}
+
+	p.list.moveCursor(1)
+}
+
+// complete extends the query to the longest path prefix shared by all
+// entries starting with it, like shell path completion.
+func (p *FilePicker) complete() {
+	query := p.input.Value()
+
+	var completions []string
+	for _, entry := range p.entries {
+		if strings.HasPrefix(entry, query) {
+			completions = append(completions, entry)
+		}
+	}
+	if len(completions) == 0 {
+		return
+	}
+
+	prefix := completions[0]
+	for _, completion := range completions[1:] {
+		prefix = commonPrefix(prefix, completion)
+	}
+
+	if prefix != query {
+		p.setQuery(prefix)
+	}
+}
+
+// chosenFiles returns the selected files, or the entry under the cursor if
+// nothing is selected. Directories are expanded to the files inside them.
+func (p *FilePicker) chosenFiles() []string {
+	var chosen []string
+	for _, entry := range p.entries {
+		if p.selected[entry] {
+			chosen = append(chosen, entry)
+		}
+	}
+	if len(chosen) == 0 {
+		if entry, ok := p.current(); ok {
+			chosen = append(chosen, entry.path)
+		}
+	}
+
+	var result []string
+	for _, path := range chosen {
+		if !strings.HasSuffix(path, "/") {
+			result = append(result, path)
+			continue
+		}
+		for _, file := range p.files {
+			if strings.HasPrefix(file, path) {
+				result = append(result, file)
+			}
+		}
+	}
+
+	// a file can be chosen directly and through its directory
+	slices.Sort(result)
+	return slices.Compact(result)
+}
+
+func commonPrefix(a, b string) string {
+	i := 0
+	for i < len(a) && i < len(b) && a[i] == b[i] {
+		i++
+	}
+	return a[:i]
+}
@@ -0,0 +1,155 @@
+package app
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"mark/internal/domain"
+
+	tea "github.com/charmbracelet/bubbletea/v2"
+	"github.com/gkampitakis/go-snaps/snaps"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var pickerFiles = []string{
+	"README.md",
+	"cmd/root.go",
+	"internal/app/app.go",
+	"internal/app/main.go",
+	"internal/domain/session.go",
+}
+
+func newTestPicker() (*FilePicker, *[]string) {
+	var added []string
+	picker := NewFilePicker(pickerFiles, DefaultKeyMap().Picker, func(paths []string) error {
+		added = paths
+		return nil
+	})
+	picker.SetSize(40, 14)
+	return picker, &added
+}
+
+func pickerPaths(p *FilePicker) []string {
+	var paths []string
+	for _, match := range p.matches {
+		paths = append(paths, match.path)
+	}
+	return paths
+}
+
+func typeInPicker(app *App, p *FilePicker, text string) {
+	for _, r := range text {
+		p.Update(app, keyPress(r))
+	}
+}
+
+func TestFilePicker(t *testing.T) {
+	t.Parallel()
+
+	t.Run("lists directories and files", func(t *testing.T) {
+		t.Parallel()
+
+		picker, _ := newTestPicker()
+		assert.Equal(t, []string{
+			"cmd/", "internal/", "internal/app/", "internal/domain/",
+			"README.md", "cmd/root.go", "internal/app/app.go", "internal/app/main.go", "internal/domain/session.go",
+		}, pickerPaths(picker))
+	})
+
+	t.Run("fuzzy matches", func(t *testing.T) {
+		t.Parallel()
+
+		app := bareApp(t)
+		picker, _ := newTestPicker()
+		typeInPicker(&app, picker, "iapm")
+
+		assert.Equal(t, []string{"internal/app/main.go"}, pickerPaths(picker))
+		snaps.MatchStandaloneSnapshot(t, picker.View())
+	})
+
+	t.Run("restricts matches to the directory in the query", func(t *testing.T) {
+		t.Parallel()
+
+		app := bareApp(t)
+		picker, _ := newTestPicker()
+		typeInPicker(&app, picker, "internal/app/")
+		assert.Equal(t, []string{"internal/app/app.go", "internal/app/main.go"}, pickerPaths(picker))
+
+		typeInPicker(&app, picker, "mn")
+		assert.Equal(t, []string{"internal/app/main.go"}, pickerPaths(picker))
+	})
+
+	t.Run("tab completes the common prefix", func(t *testing.T) {
+		t.Parallel()
+
+		app := bareApp(t)
+		picker, _ := newTestPicker()
+		typeInPicker(&app, picker, "int")
+		picker.Update(&app, keyPress(tea.KeyTab))
+		assert.Equal(t, "internal/", picker.input.Value())
+
+		typeInPicker(&app, picker, "a")
+		picker.Update(&app, keyPress(tea.KeyTab))
+		assert.Equal(t, "internal/app/", picker.input.Value())
+	})
+
+	t.Run("right expands the directory under the cursor", func(t *testing.T) {
+		t.Parallel()
+
+		app := bareApp(t)
+		picker, _ := newTestPicker()
+		picker.Update(&app, keyPress(tea.KeyDown))
+		picker.Update(&app, keyPress(tea.KeyRight))
+
+		assert.Equal(t, "internal/", picker.input.Value())
+	})
+
+	t.Run("enter adds the file under the cursor", func(t *testing.T) {
+		t.Parallel()
+
+		app := bareApp(t)
+		picker, added := newTestPicker()
+		typeInPicker(&app, picker, "root")
+		picker.Update(&app, keyPress(tea.KeyEnter))
+
+		assert.Equal(t, []string{"cmd/root.go"}, *added)
+	})
+
+	t.Run("enter adds the selected files and directories", func(t *testing.T) {
+		t.Parallel()
+
+		app := bareApp(t)
+		picker, added := newTestPicker()
+		picker.Update(&app, keymod(tea.ModCtrl, 's')) // cmd/
+		picker.Update(&app, keyPress(tea.KeyDown))
+		picker.Update(&app, keyPress(tea.KeyDown))
+		picker.Update(&app, keymod(tea.ModCtrl, 's')) // internal/domain/
+		typeInPicker(&app, picker, "main")
+		picker.Update(&app, keymod(tea.ModCtrl, 's')) // internal/app/main.go
+
+		snaps.MatchStandaloneSnapshot(t, picker.View())
+
+		picker.Update(&app, keyPress(tea.KeyEnter))
+		assert.Equal(t, []string{"cmd/root.go", "internal/app/main.go", "internal/domain/session.go"}, *added)
+	})
+
+	t.Run("adds files to the context", func(t *testing.T) {
+		t.Parallel()
+
+		app := bareApp(t)
+		require.NoError(t, os.WriteFile(filepath.Join(app.cwd, "notes.txt"), []byte("notes"), 0o644))
+
+		app = update(app, keyPress('f'))
+		assert.Nil(t, app.dialog, "the files are listed in the background")
+		app = update(app, app.indexFiles()())
+		app = typeText(app, "notes")
+		app = update(app, keyPress(tea.KeyEnter))
+
+		require.Nil(t, app.dialog)
+		items := app.session.Context().Items()
+		require.Len(t, items, 1)
+		assert.Equal(t, "notes.txt", filepath.Base(items[0].(domain.ContextItemFile).Path()))
+	})
+}
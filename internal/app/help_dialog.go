@@ -0,0 +1,95 @@
+package app
+
+import (
+	"fmt"
+	"strings"
+
+	"mark/internal/util"
+
+	"github.com/charmbracelet/bubbles/v2/key"
+	tea "github.com/charmbracelet/bubbletea/v2"
+	"github.com/charmbracelet/lipgloss/v2"
+	"github.com/charmbracelet/x/ansi"
+)
+
+var helpKeyStyle = lipgloss.NewStyle().Foreground(focusColor).Bold(true)
+
+// helpRows is the maximum number of bindings shown at once.
+const helpRows = 16
+
+// HelpDialog lists the key bindings of the focused component.
+type HelpDialog struct {
+	width    int
+	rows     int // number of bindings shown at once
+	bindings []key.Binding
+	offset   int // index of the first visible binding
+}
+
+func NewHelpDialog(bindings []key.Binding) *HelpDialog {
+	var enabled []key.Binding
+	for _, b := range bindings {
+		if b.Enabled() {
+			enabled = append(enabled, b)
+		}
+	}
+
+	return &HelpDialog{
+		rows:     helpRows,
+		bindings: enabled,
+	}
+}
+
+func (dialog *HelpDialog) Focus() {}
+
+func (dialog *HelpDialog) Blur() {}
+
+func (dialog *HelpDialog) SetSize(width, height int) {
+	dialog.width = width
+	dialog.rows = util.Clamp(height-2, 1, helpRows) // borders
+	dialog.offset = util.Clamp(dialog.offset, 0, max(len(dialog.bindings)-dialog.rows, 0))
+}
+
+func (dialog *HelpDialog) Update(app *App, msg tea.Msg) tea.Cmd {
+	switch msg := msg.(type) {
+	case tea.KeyPressMsg:
+		keys := app.keys.Help
+		maxOffset := max(len(dialog.bindings)-dialog.rows, 0)
+
+		switch {
+		case key.Matches(msg, keys.Close):
+			app.hideDialog()
+		case key.Matches(msg, keys.Up):
+			dialog.offset = util.Clamp(dialog.offset-1, 0, maxOffset)
+		case key.Matches(msg, keys.Down):
+			dialog.offset = util.Clamp(dialog.offset+1, 0, maxOffset)
+		}
+	}
+
+	return nil
+}
+
+func (dialog *HelpDialog) View() string {
+	width := max(dialog.width-2, 0) // borders
+
+	// align the descriptions
+	keysWidth := 0
+	for _, b := range dialog.bindings {
+		keysWidth = max(keysWidth, ansi.StringWidth(b.Help().Key))
+	}
+
+	var lines []string
+	for _, b := range dialog.bindings[dialog.offset:min(dialog.offset+dialog.rows, len(dialog.bindings))] {
+		help := b.Help()
+		keys := helpKeyStyle.Render(help.Key + strings.Repeat(" ", keysWidth-ansi.StringWidth(help.Key)))
+		lines = append(lines, ansi.Truncate(keys+"  "+help.Desc, width, "…"))
+	}
+
+	title := "Keys"
+	if len(dialog.bindings) > dialog.rows {
+		title += fmt.Sprintf(" (%d-%d of %d)", dialog.offset+1, dialog.offset+len(lines), len(dialog.bindings))
+	}
+
+	content := lipgloss.NewStyle().Width(width).Render(strings.Join(lines, "\n"))
+
+	return util.RenderBorderWithTitle(content, focusedBorderStyle, title, focusedPanelTitleStyle)
+}
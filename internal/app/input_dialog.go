@@ -1,8 +1,9 @@
 package app
 
 import (
-	"log/slog"
+	"mark/internal/util"
 
+	"github.com/charmbracelet/bubbles/v2/key"
 	"github.com/charmbracelet/bubbles/v2/textinput"
 	tea "github.com/charmbracelet/bubbletea/v2"
 	"github.com/charmbracelet/lipgloss/v2"
@@ -11,20 +12,32 @@ import (
 type InputDialog struct {
 	width    int
 	height   int
+	title    string
 	input    textinput.Model
-	callback func(v string) error
+	callback func(app *App, v string) error
 }
 
-func NewInputDialog(callback func(v string) error) *InputDialog {
+func NewInputDialog(callback func(app *App, v string) error) *InputDialog {
 	input := textinput.New()
 	input.Focus()
 
 	return &InputDialog{
-		input: input,
+		input:    input,
 		callback: callback,
 	}
 }
 
+// SetTitle shows title on the border of the dialog.
+func (dialog *InputDialog) SetTitle(title string) {
+	dialog.title = title
+}
+
+// SetValue sets the value being edited.
+func (dialog *InputDialog) SetValue(v string) {
+	dialog.input.SetValue(v)
+	dialog.input.CursorEnd()
+}
+
 func (dialog *InputDialog) Focus() {
 	dialog.input.Focus()
 }
@@ -44,15 +57,14 @@ func (dialog *InputDialog) Update(app *App, msg tea.Msg) tea.Cmd {
 
 	switch msg := msg.(type) {
 	case tea.KeyPressMsg:
-		switch msg.String() {
-		case "enter":
-			err := dialog.callback(dialog.input.Value())
-			if err != nil {
-				slog.Error("Error") // TODO handle error
-				return nil
-			}
+		switch {
+		case key.Matches(msg, app.keys.Input.Confirm):
+			// hide first, the callback may show another dialog
 			app.hideDialog()
-		case "esc":
+			if err := dialog.callback(app, dialog.input.Value()); err != nil {
+				app.handleError(err)
+			}
+		case key.Matches(msg, app.keys.Input.Cancel):
 			app.hideDialog()
 		default:
 			var cmd tea.Cmd
@@ -65,5 +77,8 @@ func (dialog *InputDialog) Update(app *App, msg tea.Msg) tea.Cmd {
 }
 
 func (dialog *InputDialog) View() string {
+	if dialog.title != "" {
+		return util.RenderBorderWithTitle(dialog.input.View(), focusedBorderStyle, dialog.title, focusedPanelTitleStyle)
+	}
 	return lipgloss.NewStyle().BorderStyle(lipgloss.RoundedBorder()).Render(dialog.input.View())
 }
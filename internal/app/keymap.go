@@ -0,0 +1,537 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/v2/key"
+)
+
+// KeyMap holds the key bindings of the App, grouped by the component
+// handling them. Every binding has a name, like "context.delete", used to
+// override its keys in the config.
+type KeyMap struct {
+	Main          MainKeys
+	Context       ContextKeys
+	Messages      MessagesKeys
+	Search        SearchKeys
+	Prompt        PromptKeys
+	Picker        PickerKeys
+	CodeBlocks    ChoiceKeys
+	Confirm       ConfirmKeys
+	Input         InputKeys
+	Help          HelpKeys
+	Error         ErrorKeys
+	Notifications NotificationKeys
+	Personas      ChoiceKeys
+}
+
+// MainKeys are handled by Main while the context or messages pane has
+// focus.
+type MainKeys struct {
+	Quit          key.Binding
+	Help          key.Binding
+	FocusPrompt   key.Binding
+	Search        key.Binding
+	CopyReply     key.Binding
+	CopyCode      key.Binding
+	SaveCode      key.Binding
+	NextPane      key.Binding
+	PrevPane      key.Binding
+	Zoom          key.Binding
+	GrowSidebar   key.Binding
+	ShrinkSidebar key.Binding
+	Run           key.Binding
+	Cancel        key.Binding
+	NewSession    key.Binding
+	Notifications key.Binding
+	Persona       key.Binding
+	ScrollDown    key.Binding
+	ScrollUp      key.Binding
+}
+
+// ScrollKeys move through the content of the focused pane.
+type ScrollKeys struct {
+	Up           key.Binding
+	Down         key.Binding
+	PageUp       key.Binding
+	PageDown     key.Binding
+	HalfPageUp   key.Binding
+	HalfPageDown key.Binding
+	Top          key.Binding
+	Bottom       key.Binding
+}
+
+// ContextKeys are handled by the context items list.
+type ContextKeys struct {
+	ScrollKeys
+	AddFile key.Binding
+	AddText key.Binding
+	Edit    key.Binding
+	Delete  key.Binding
+}
+
+// MessagesKeys are handled while the messages pane has focus.
+type MessagesKeys struct {
+	ScrollKeys
+	NextMatch key.Binding
+	PrevMatch key.Binding
+}
+
+// SearchKeys are handled while a search query is typed.
+type SearchKeys struct {
+	Confirm key.Binding
+	Cancel  key.Binding
+}
+
+// PromptKeys are handled while the prompt has focus. Other keys are typed
+// into the prompt.
+type PromptKeys struct {
+	Quit          key.Binding
+	Help          key.Binding
+	NextPane      key.Binding
+	PrevPane      key.Binding
+	Zoom          key.Binding
+	Submit        key.Binding
+	Editor        key.Binding
+	Leave         key.Binding
+	HistoryPrev   key.Binding
+	HistoryNext   key.Binding
+	Notifications key.Binding
+}
+
+// PickerKeys are handled by the file picker.
+type PickerKeys struct {
+	Up       key.Binding
+	Down     key.Binding
+	Select   key.Binding
+	Complete key.Binding
+	Expand   key.Binding
+	Add      key.Binding
+	Close    key.Binding
+}
+
+// ChoiceKeys are handled by the pickers choosing one entry, like the code
+// block and persona pickers.
+type ChoiceKeys struct {
+	Up     key.Binding
+	Down   key.Binding
+	Choose key.Binding
+	Close  key.Binding
+}
+
+// ConfirmKeys are handled by the confirmation dialog.
+type ConfirmKeys struct {
+	Yes  key.Binding
+	No   key.Binding
+	Up   key.Binding
+	Down key.Binding
+}
+
+// InputKeys are handled by the input dialog.
+type InputKeys struct {
+	Confirm key.Binding
+	Cancel  key.Binding
+}
+
+// HelpKeys are handled by the help overlay.
+type HelpKeys struct {
+	Close key.Binding
+	Up    key.Binding
+	Down  key.Binding
+}
+
+// ErrorKeys are handled by the error dialog. Other keys close it.
+type ErrorKeys struct {
+	Retry       key.Binding
+	SetAPIKey   key.Binding
+	TrimContext key.Binding
+	Close       key.Binding
+}
+
+// NotificationKeys are handled by the notifications panel.
+type NotificationKeys struct {
+	Close key.Binding
+	Up    key.Binding
+	Down  key.Binding
+}
+
+// keyHints describes the enabled bindings on one line, like
+// "y confirm · n/esc cancel", for the footers of dialogs.
+func keyHints(bindings ...key.Binding) string {
+	var hints []string
+	for _, b := range bindings {
+		if b.Enabled() {
+			hints = append(hints, b.Help().Key+" "+b.Help().Desc)
+		}
+	}
+	return strings.Join(hints, " · ")
+}
+
+func binding(desc string, keys ...string) key.Binding {
+	return key.NewBinding(key.WithKeys(keys...), key.WithHelp(strings.Join(keys, "/"), desc))
+}
+
+func scrollKeys(item string) ScrollKeys {
+	return ScrollKeys{
+		Up:           binding("previous "+item, "up", "k"),
+		Down:         binding("next "+item, "down", "j"),
+		PageUp:       binding("page up", "pgup"),
+		PageDown:     binding("page down", "pgdown"),
+		HalfPageUp:   binding("half page up", "ctrl+u"),
+		HalfPageDown: binding("half page down", "ctrl+d"),
+		Top:          binding("go to top", "home", "g"),
+		Bottom:       binding("go to bottom", "end", "G"),
+	}
+}
+
+// DefaultKeyMap returns the default key bindings.
+func DefaultKeyMap() *KeyMap {
+	return &KeyMap{
+		Main: MainKeys{
+			Quit:          binding("quit", "ctrl+c"),
+			Help:          binding("show keys", "?"),
+			FocusPrompt:   binding("write a prompt", "i"),
+			Search:        binding("search messages", "/"),
+			CopyReply:     binding("copy reply", "y"),
+			CopyCode:      binding("copy code block", "Y"),
+			SaveCode:      binding("save code block", "s"),
+			NextPane:      binding("next pane", "tab"),
+			PrevPane:      binding("previous pane", "shift+tab"),
+			Zoom:          binding("zoom pane", "z"),
+			GrowSidebar:   binding("grow sidebar", ">"),
+			ShrinkSidebar: binding("shrink sidebar", "<"),
+			Run:           binding("run agent", "enter"),
+			Cancel:        binding("cancel run", "esc"),
+			NewSession:    binding("new session", "ctrl+n"),
+			Notifications: binding("show notifications", "L"),
+			Persona:       binding("choose persona", "p"),
+			ScrollDown:    binding("scroll messages down", "shift+j"),
+			ScrollUp:      binding("scroll messages up", "shift+k"),
+		},
+		Context: ContextKeys{
+			ScrollKeys: scrollKeys("item"),
+			AddFile:    binding("add files", "f"),
+			AddText:    binding("add text", "n"),
+			Edit:       binding("edit item", "e"),
+			Delete:     binding("delete item", "d"),
+		},
+		Messages: MessagesKeys{
+			ScrollKeys: scrollKeys("line"),
+			NextMatch:  binding("next match", "n"),
+			PrevMatch:  binding("previous match", "N"),
+		},
+		Search: SearchKeys{
+			Confirm: binding("keep matches", "enter"),
+			Cancel:  binding("clear search", "esc"),
+		},
+		Prompt: PromptKeys{
+			Quit:          binding("quit", "ctrl+c"),
+			Help:          binding("show keys", "f1"),
+			NextPane:      binding("next pane", "tab"),
+			PrevPane:      binding("previous pane", "shift+tab"),
+			Zoom:          binding("zoom pane", "alt+z"),
+			Submit:        binding("send prompt", "ctrl+enter"),
+			Editor:        binding("edit in $EDITOR", "ctrl+o"),
+			Leave:         binding("back to context", "esc"),
+			HistoryPrev:   binding("previous prompt", "up"),
+			HistoryNext:   binding("next prompt", "down"),
+			Notifications: binding("show notifications", "f2"),
+		},
+		Picker: PickerKeys{
+			Up:       binding("previous entry", "up", "ctrl+p"),
+			Down:     binding("next entry", "down", "ctrl+n"),
+			Select:   binding("select", "ctrl+s"),
+			Complete: binding("complete", "tab"),
+			Expand:   binding("open directory", "right"),
+			Add:      binding("add", "enter"),
+			Close:    binding("close", "esc"),
+		},
+		CodeBlocks: ChoiceKeys{
+			Up:     binding("previous block", "up", "k", "ctrl+p"),
+			Down:   binding("next block", "down", "j", "ctrl+n"),
+			Choose: binding("choose block", "enter"),
+			Close:  binding("close", "esc", "q"),
+		},
+		Confirm: ConfirmKeys{
+			Yes:  binding("confirm", "y"),
+			No:   binding("cancel", "n", "esc"),
+			Up:   binding("scroll up", "up", "k"),
+			Down: binding("scroll down", "down", "j"),
+		},
+		Input: InputKeys{
+			Confirm: binding("confirm", "enter"),
+			Cancel:  binding("cancel", "esc"),
+		},
+		Help: HelpKeys{
+			Close: binding("close", "esc", "?", "q"),
+			Up:    binding("scroll up", "up", "k"),
+			Down:  binding("scroll down", "down", "j"),
+		},
+		Error: ErrorKeys{
+			Retry:       binding("retry", "r"),
+			SetAPIKey:   binding("enter API key", "k"),
+			TrimContext: binding("remove largest item", "d"),
+			Close:       binding("close", "esc", "enter", "q"),
+		},
+		Notifications: NotificationKeys{
+			Close: binding("close", "esc", "q"),
+			Up:    binding("scroll up", "up", "k"),
+			Down:  binding("scroll down", "down", "j"),
+		},
+		Personas: ChoiceKeys{
+			Up:     binding("previous persona", "up", "k", "ctrl+p"),
+			Down:   binding("next persona", "down", "j", "ctrl+n"),
+			Choose: binding("choose persona", "enter"),
+			Close:  binding("close", "esc", "q"),
+		},
+	}
+}
+
+// namedBinding is a binding with the name used in the config.
+type namedBinding struct {
+	name    string
+	binding *key.Binding
+}
+
+// groups returns the bindings of every group by group name.
+func (k *KeyMap) groups() map[string][]namedBinding {
+	return map[string][]namedBinding{
+		"main": {
+			{"main.quit", &k.Main.Quit},
+			{"main.help", &k.Main.Help},
+			{"main.focus_prompt", &k.Main.FocusPrompt},
+			{"main.search", &k.Main.Search},
+			{"main.copy_reply", &k.Main.CopyReply},
+			{"main.copy_code", &k.Main.CopyCode},
+			{"main.save_code", &k.Main.SaveCode},
+			{"main.next_pane", &k.Main.NextPane},
+			{"main.prev_pane", &k.Main.PrevPane},
+			{"main.zoom", &k.Main.Zoom},
+			{"main.grow_sidebar", &k.Main.GrowSidebar},
+			{"main.shrink_sidebar", &k.Main.ShrinkSidebar},
+			{"main.run", &k.Main.Run},
+			{"main.cancel", &k.Main.Cancel},
+			{"main.new_session", &k.Main.NewSession},
+			{"main.notifications", &k.Main.Notifications},
+			{"main.persona", &k.Main.Persona},
+			{"main.scroll_down", &k.Main.ScrollDown},
+			{"main.scroll_up", &k.Main.ScrollUp},
+		},
+		"context": append(scrollBindings("context", &k.Context.ScrollKeys),
+			namedBinding{"context.add_file", &k.Context.AddFile},
+			namedBinding{"context.add_text", &k.Context.AddText},
+			namedBinding{"context.edit", &k.Context.Edit},
+			namedBinding{"context.delete", &k.Context.Delete},
+		),
+		"messages": append(scrollBindings("messages", &k.Messages.ScrollKeys),
+			namedBinding{"messages.next_match", &k.Messages.NextMatch},
+			namedBinding{"messages.prev_match", &k.Messages.PrevMatch},
+		),
+		"search": {
+			{"search.confirm", &k.Search.Confirm},
+			{"search.cancel", &k.Search.Cancel},
+		},
+		"prompt": {
+			{"prompt.quit", &k.Prompt.Quit},
+			{"prompt.help", &k.Prompt.Help},
+			{"prompt.next_pane", &k.Prompt.NextPane},
+			{"prompt.prev_pane", &k.Prompt.PrevPane},
+			{"prompt.zoom", &k.Prompt.Zoom},
+			{"prompt.submit", &k.Prompt.Submit},
+			{"prompt.editor", &k.Prompt.Editor},
+			{"prompt.leave", &k.Prompt.Leave},
+			{"prompt.history_prev", &k.Prompt.HistoryPrev},
+			{"prompt.history_next", &k.Prompt.HistoryNext},
+			{"prompt.notifications", &k.Prompt.Notifications},
+		},
+		"picker": {
+			{"picker.up", &k.Picker.Up},
+			{"picker.down", &k.Picker.Down},
+			{"picker.select", &k.Picker.Select},
+			{"picker.complete", &k.Picker.Complete},
+			{"picker.expand", &k.Picker.Expand},
+			{"picker.add", &k.Picker.Add},
+			{"picker.close", &k.Picker.Close},
+		},
+		"codeblocks": {
+			{"codeblocks.up", &k.CodeBlocks.Up},
+			{"codeblocks.down", &k.CodeBlocks.Down},
+			{"codeblocks.choose", &k.CodeBlocks.Choose},
+			{"codeblocks.close", &k.CodeBlocks.Close},
+		},
+		"confirm": {
+			{"confirm.yes", &k.Confirm.Yes},
+			{"confirm.no", &k.Confirm.No},
+			{"confirm.up", &k.Confirm.Up},
+			{"confirm.down", &k.Confirm.Down},
+		},
+		"input": {
+			{"input.confirm", &k.Input.Confirm},
+			{"input.cancel", &k.Input.Cancel},
+		},
+		"help": {
+			{"help.close", &k.Help.Close},
+			{"help.up", &k.Help.Up},
+			{"help.down", &k.Help.Down},
+		},
+		"error": {
+			{"error.retry", &k.Error.Retry},
+			{"error.set_api_key", &k.Error.SetAPIKey},
+			{"error.trim_context", &k.Error.TrimContext},
+			{"error.close", &k.Error.Close},
+		},
+		"notifications": {
+			{"notifications.close", &k.Notifications.Close},
+			{"notifications.up", &k.Notifications.Up},
+			{"notifications.down", &k.Notifications.Down},
+		},
+		"personas": {
+			{"personas.up", &k.Personas.Up},
+			{"personas.down", &k.Personas.Down},
+			{"personas.choose", &k.Personas.Choose},
+			{"personas.close", &k.Personas.Close},
+		},
+	}
+}
+
+func scrollBindings(group string, s *ScrollKeys) []namedBinding {
+	return []namedBinding{
+		{group + ".up", &s.Up},
+		{group + ".down", &s.Down},
+		{group + ".page_up", &s.PageUp},
+		{group + ".page_down", &s.PageDown},
+		{group + ".half_page_up", &s.HalfPageUp},
+		{group + ".half_page_down", &s.HalfPageDown},
+		{group + ".top", &s.Top},
+		{group + ".bottom", &s.Bottom},
+	}
+}
+
+// activeTogether lists the groups handling keys at the same time. A key
+// can't be bound twice within them.
+var activeTogether = [][]string{
+	{"main", "context"},
+	{"main", "messages"},
+	{"search"},
+	{"prompt"},
+	{"picker"},
+	{"codeblocks"},
+	{"confirm"},
+	{"input"},
+	{"help"},
+	{"error"},
+	{"notifications"},
+	{"personas"},
+}
+
+// NewKeyMap returns the default key bindings with overrides applied.
+// Overrides map binding names to keys, an empty list disables the binding.
+// Returns an error for unknown binding names and for keys bound twice.
+func NewKeyMap(overrides map[string][]string) (*KeyMap, error) {
+	keys := DefaultKeyMap()
+
+	bindings := map[string]*key.Binding{}
+	for _, group := range keys.groups() {
+		for _, b := range group {
+			bindings[b.name] = b.binding
+		}
+	}
+
+	var errs []error
+	for _, name := range slices.Sorted(maps.Keys(overrides)) {
+		override := overrides[name]
+		b, ok := bindings[name]
+		if !ok {
+			errs = append(errs, fmt.Errorf("unknown key binding %q", name))
+			continue
+		}
+
+		if len(override) == 0 {
+			b.SetEnabled(false)
+			continue
+		}
+
+		b.SetKeys(override...)
+		b.SetHelp(strings.Join(override, "/"), b.Help().Desc)
+	}
+
+	errs = append(errs, keys.conflicts()...)
+
+	if len(errs) > 0 {
+		return nil, fmt.Errorf("invalid key bindings: %w", errors.Join(errs...))
+	}
+
+	return keys, nil
+}
+
+// conflicts returns an error for every key bound twice in groups active
+// together.
+func (k *KeyMap) conflicts() []error {
+	groups := k.groups()
+
+	var errs []error
+	for _, names := range activeTogether {
+		owners := map[string]string{}
+		for _, name := range names {
+			for _, b := range groups[name] {
+				if !b.binding.Enabled() {
+					continue
+				}
+
+				for _, keyName := range b.binding.Keys() {
+					if owner, ok := owners[keyName]; ok {
+						errs = append(errs, fmt.Errorf("key %q is bound to both %s and %s", keyName, owner, b.name))
+						continue
+					}
+					owners[keyName] = b.name
+				}
+			}
+		}
+	}
+
+	return errs
+}
+
+func (s ScrollKeys) bindings() []key.Binding {
+	return []key.Binding{s.Up, s.Down, s.PageUp, s.PageDown, s.HalfPageUp, s.HalfPageDown, s.Top, s.Bottom}
+}
+
+// mainHelp returns the bindings of Main, shared by the context and messages
+// panes.
+func (k *KeyMap) mainHelp() []key.Binding {
+	return []key.Binding{
+		k.Main.FocusPrompt, k.Main.Search, k.Main.CopyReply, k.Main.CopyCode, k.Main.SaveCode,
+		k.Main.Run, k.Main.Cancel, k.Main.NewSession, k.Main.Persona, k.Main.Notifications,
+		k.Main.NextPane, k.Main.PrevPane, k.Main.Zoom, k.Main.GrowSidebar, k.Main.ShrinkSidebar,
+		k.Main.ScrollDown, k.Main.ScrollUp, k.Main.Help, k.Main.Quit,
+	}
+}
+
+// ContextHelp returns the bindings available while the context pane has
+// focus.
+func (k *KeyMap) ContextHelp() []key.Binding {
+	bindings := []key.Binding{k.Context.AddFile, k.Context.AddText, k.Context.Edit, k.Context.Delete}
+	bindings = append(bindings, k.Context.ScrollKeys.bindings()...)
+	return append(bindings, k.mainHelp()...)
+}
+
+// MessagesHelp returns the bindings available while the messages pane has
+// focus.
+func (k *KeyMap) MessagesHelp() []key.Binding {
+	bindings := []key.Binding{k.Messages.NextMatch, k.Messages.PrevMatch}
+	bindings = append(bindings, k.Messages.ScrollKeys.bindings()...)
+	return append(bindings, k.mainHelp()...)
+}
+
+// PromptHelp returns the bindings available while the prompt has focus.
+func (k *KeyMap) PromptHelp() []key.Binding {
+	return []key.Binding{
+		k.Prompt.Submit, k.Prompt.Editor, k.Prompt.HistoryPrev, k.Prompt.HistoryNext,
+		k.Prompt.NextPane, k.Prompt.PrevPane, k.Prompt.Zoom,
+		k.Prompt.Leave, k.Prompt.Notifications, k.Prompt.Help, k.Prompt.Quit,
+	}
+}
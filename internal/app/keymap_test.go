@@ -0,0 +1,159 @@
+package app
+
+import (
+	"testing"
+
+	"github.com/charmbracelet/bubbles/v2/key"
+	tea "github.com/charmbracelet/bubbletea/v2"
+	"github.com/gkampitakis/go-snaps/snaps"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKeyMap(t *testing.T) {
+	t.Parallel()
+
+	t.Run("defaults have no conflicts", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := NewKeyMap(nil)
+		require.NoError(t, err)
+	})
+
+	t.Run("overrides", func(t *testing.T) {
+		t.Parallel()
+
+		keys, err := NewKeyMap(map[string][]string{
+			"context.delete":   {"x", "delete"},
+			"main.new_session": {},
+		})
+		require.NoError(t, err)
+
+		assert.Equal(t, []string{"x", "delete"}, keys.Context.Delete.Keys())
+		assert.Equal(t, "x/delete", keys.Context.Delete.Help().Key)
+		assert.False(t, keys.Main.NewSession.Enabled())
+	})
+
+	t.Run("unknown binding", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := NewKeyMap(map[string][]string{"context.remove": {"x"}})
+		assert.EqualError(t, err, "invalid key bindings: unknown key binding \"context.remove\"")
+	})
+
+	t.Run("conflicts", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := NewKeyMap(map[string][]string{"context.delete": {"f"}})
+		assert.EqualError(t, err, "invalid key bindings: key \"f\" is bound to both context.add_file and context.delete")
+
+		_, err = NewKeyMap(map[string][]string{"main.new_session": {"e"}})
+		assert.EqualError(t, err, "invalid key bindings: key \"e\" is bound to both main.new_session and context.edit")
+	})
+
+	t.Run("keys bound in components not active together", func(t *testing.T) {
+		t.Parallel()
+
+		// the picker is a dialog, main doesn't get keys while it's open
+		_, err := NewKeyMap(map[string][]string{"picker.select": {"ctrl+n"}, "picker.down": {"down"}})
+		require.NoError(t, err)
+	})
+
+	t.Run("overrides are used by the App", func(t *testing.T) {
+		t.Parallel()
+
+		keys, err := NewKeyMap(map[string][]string{"context.add_text": {"a"}})
+		require.NoError(t, err)
+
+		app := bareApp(t)
+		app.SetKeyMap(keys)
+
+		app = update(app, keyPress('n'))
+		assert.Nil(t, app.dialog)
+
+		app = update(app, keyPress('a'))
+		assert.IsType(t, &InputDialog{}, app.dialog)
+	})
+
+	t.Run("hints show the configured keys", func(t *testing.T) {
+		t.Parallel()
+
+		keys, err := NewKeyMap(map[string][]string{
+			"confirm.yes":          {"o"},
+			"picker.add":           {"ctrl+a"},
+			"prompt.submit":        {"alt+enter"},
+			"main.notifications":   {"H"},
+			"notifications.close":  {"esc"},
+			"prompt.notifications": {},
+		})
+		require.NoError(t, err)
+
+		app := bareApp(t)
+		app.SetKeyMap(keys)
+
+		confirm := NewConfirmDialog("Overwrite?", "diff", keys.Confirm, nil)
+		confirm.SetSize(60, 14)
+		assert.Contains(t, confirm.View(), "o confirm · n/esc cancel")
+		assert.Contains(t, render(t, app), "alt+enter to send")
+
+		picker := NewFilePicker(pickerFiles, keys.Picker, nil)
+		picker.SetSize(60, 14)
+		assert.Contains(t, picker.View(), "ctrl+a add")
+
+		// the key showing the notifications also hides them
+		app = update(app, keyPress('H'))
+		require.IsType(t, &NotificationsPanel{}, app.dialog)
+		app = update(app, keyPress('H'))
+		assert.Nil(t, app.dialog)
+	})
+}
+
+func TestHelpDialog(t *testing.T) {
+	t.Parallel()
+
+	t.Run("bindings are listed once", func(t *testing.T) {
+		t.Parallel()
+
+		keys := DefaultKeyMap()
+		for name, bindings := range map[string][]key.Binding{
+			"context":  keys.ContextHelp(),
+			"messages": keys.MessagesHelp(),
+			"prompt":   keys.PromptHelp(),
+		} {
+			seen := map[string]bool{}
+			for _, b := range bindings {
+				help := b.Help().Key + " " + b.Help().Desc
+				assert.False(t, seen[help], "%s lists %q twice", name, help)
+				seen[help] = true
+			}
+		}
+	})
+
+	t.Run("context", func(t *testing.T) {
+		t.Parallel()
+
+		app := bareApp(t)
+		app = update(app, tea.WindowSizeMsg{Width: 80, Height: 24})
+		app = update(app, keyPress('?'))
+		require.IsType(t, &HelpDialog{}, app.dialog)
+		snaps.MatchStandaloneSnapshot(t, app.View())
+
+		app = update(app, keyPress(tea.KeyEscape))
+		assert.Nil(t, app.dialog)
+	})
+
+	t.Run("prompt", func(t *testing.T) {
+		t.Parallel()
+
+		app := bareApp(t)
+		app = update(app, tea.WindowSizeMsg{Width: 80, Height: 24})
+		app = update(app, keyPress('i'))
+		app = update(app, keyPress(tea.KeyF1))
+		require.IsType(t, &HelpDialog{}, app.dialog)
+		snaps.MatchStandaloneSnapshot(t, app.View())
+
+		app = update(app, keyPress('q'))
+		assert.Nil(t, app.dialog)
+		assert.True(t, app.main.prompt.IsFocused())
+	})
+}
@@ -1,23 +1,59 @@
 package app
 
 import (
+	"strings"
+
 	"mark/internal/util"
 
+	"github.com/charmbracelet/bubbles/v2/key"
 	"github.com/charmbracelet/bubbles/v2/viewport"
 	tea "github.com/charmbracelet/bubbletea/v2"
 	"github.com/charmbracelet/lipgloss/v2"
 )
 
+// pane identifies the panes of Main that can have focus.
+type pane int
+
+// panes are ordered as they are cycled through.
+const (
+	paneContext pane = iota
+	paneMessages
+	panePrompt
+	paneCount
+)
+
+const (
+	// promptHeight is the number of lines of the prompt input.
+	promptHeight = 3
+
+	// minPaneWidth is the minimum width of the sidebar and the messages.
+	minPaneWidth = 12
+
+	// sidebarStep is the number of columns the sidebar grows or shrinks by.
+	sidebarStep = 4
+)
+
 type Main struct {
 	contextItemsList *ContextItemsList
 	messagesViewport viewport.Model
+	search           messageSearch
+	messages         string // rendered messages, before highlighting
+	prompt           *PromptInput
+
+	hasFocus    bool
+	focusedPane pane
+	zoomed      bool // the focused pane takes all the space
 
-	hasFocus bool
+	width   int
+	height  int
+	sidebar int // width of the sidebar set by the user, 0 for the default
 }
 
 func NewMain() *Main {
 	main := &Main{
 		contextItemsList: NewContextItemsList(),
+		search:           newMessageSearch(),
+		prompt:           NewPromptInput(),
 	}
 
 	main.Focus()
@@ -25,99 +61,412 @@ func NewMain() *Main {
 	return main
 }
 
+// Focus gives focus to Main, restoring the focus of the last focused pane.
 func (main *Main) Focus() {
 	main.hasFocus = true
-	main.contextItemsList.Focus()
+	main.focusPane(main.focusedPane)
 }
 
 func (main *Main) Blur() {
 	main.hasFocus = false
 	main.contextItemsList.Blur()
+	main.prompt.Blur()
+}
+
+// focusPane moves the focus to the given pane.
+func (main *Main) focusPane(p pane) {
+	main.focusedPane = p
+
+	main.contextItemsList.Blur()
+	main.prompt.Blur()
+
+	switch p {
+	case paneContext:
+		main.contextItemsList.Focus()
+	case panePrompt:
+		main.prompt.Focus()
+	}
+
+	// the zoomed pane follows the focus
+	if main.zoomed {
+		main.layout()
+	}
+}
+
+// cyclePane moves the focus delta panes away from the focused one.
+func (main *Main) cyclePane(delta int) {
+	main.focusPane(pane((int(main.focusedPane) + delta + int(paneCount)) % int(paneCount)))
+}
+
+// toggleZoom maximizes the focused pane or restores the layout.
+func (main *Main) toggleZoom() {
+	main.zoomed = !main.zoomed
+	main.layout()
+}
+
+// resizeSidebar changes the width of the sidebar by delta columns.
+func (main *Main) resizeSidebar(delta int) {
+	main.sidebar = main.sidebarWidth() + delta
+	main.sidebar = main.sidebarWidth() // keep within bounds
+	main.layout()
+}
+
+func (main *Main) sidebarWidth() int {
+	width := main.width / 3
+	if main.sidebar > 0 {
+		width = main.sidebar
+	}
+
+	return max(util.Clamp(width, minPaneWidth, main.width-minPaneWidth), 0)
 }
 
 func (main *Main) SetSize(width, height int) {
+	main.width = width
+	main.height = height
+	main.layout()
+}
+
+// layout sizes the panes for the size of Main.
+func (main *Main) layout() {
 	borderSize := 2 // 2 times the border width
 
-	availableHeight := height - borderSize
-	sidebarWidth := width / 3
-	messagesWidth := width - sidebarWidth
+	availableHeight := main.height - borderSize
+
+	if main.zoomed {
+		availableWidth := main.width - borderSize
+		switch main.focusedPane {
+		case paneContext:
+			main.contextItemsList.SetSize(availableWidth, availableHeight)
+		case paneMessages:
+			main.setMessagesSize(availableWidth, availableHeight)
+		case panePrompt:
+			main.prompt.SetSize(availableWidth, availableHeight)
+		}
+		return
+	}
+
+	sidebarWidth := main.sidebarWidth()
+	messagesWidth := main.width - sidebarWidth
 
 	main.contextItemsList.SetSize(sidebarWidth-borderSize, availableHeight)
 
-	main.messagesViewport.SetWidth(messagesWidth - borderSize)
-	main.messagesViewport.SetHeight(availableHeight)
+	main.setMessagesSize(messagesWidth-borderSize, availableHeight-promptHeight-borderSize)
+
+	main.prompt.SetSize(messagesWidth-borderSize, promptHeight)
+}
+
+// setMessagesSize sizes the messages pane, leaving a line for the search
+// query while it's typed.
+func (main *Main) setMessagesSize(width, height int) {
+	if main.search.typing {
+		height--
+	}
+
+	main.messagesViewport.SetWidth(width)
+	main.messagesViewport.SetHeight(height)
+	main.search.input.SetWidth(width - len(main.search.input.Prompt) - 1) // prompt and cursor
+}
+
+// setMessagesContent shows the rendered messages, highlighting the matches
+// of the search.
+func (main *Main) setMessagesContent(content string) {
+	if content == main.messages {
+		return
+	}
+	main.messages = content
+
+	main.search.setLines(strings.Split(content, "\n"))
+	main.messagesViewport.SetContentLines(main.search.highlighted())
+}
+
+// startSearch focuses the messages pane to type a new search query.
+func (main *Main) startSearch() {
+	main.focusPane(paneMessages)
+	main.search.typing = true
+	main.search.input.Reset()
+	main.search.input.Focus()
+	main.search.setLines(main.search.lines)
+	main.messagesViewport.SetContentLines(main.search.highlighted())
+	main.layout()
+}
+
+// stopSearch stops typing the search query. The matches are kept
+// highlighted unless the search is cleared.
+func (main *Main) stopSearch(clear bool) {
+	main.search.typing = false
+	main.search.input.Blur()
+	if clear {
+		main.search.input.Reset()
+		main.search.setLines(main.search.lines)
+		main.messagesViewport.SetContentLines(main.search.highlighted())
+	}
+	main.layout()
+}
+
+// showMatch moves to the match delta matches away from the current one,
+// scrolling it into view.
+func (main *Main) showMatch(delta int) {
+	main.search.move(delta)
+	main.messagesViewport.SetContentLines(main.search.highlighted())
+
+	if match, ok := main.search.currentMatch(); ok {
+		main.messagesViewport.EnsureVisible(match.line, match.start, match.end)
+	}
 }
 
 func (main *Main) Update(app *App, msg tea.Msg) tea.Cmd {
+	if main.focusedPane == panePrompt {
+		return main.updatePrompt(app, msg)
+	}
+	if main.search.typing {
+		return main.updateSearch(app, msg)
+	}
+
 	var inputHandled bool
 	var cmds []tea.Cmd
 
+	keys := app.keys.Main
+
 	switch msg := msg.(type) {
 	case tea.KeyPressMsg:
-		switch msg.String() {
-		case "ctrl+c":
+		switch {
+		case key.Matches(msg, keys.Quit):
 			inputHandled = true
 			cmds = append(cmds, tea.Quit)
-		case "enter":
+		case key.Matches(msg, keys.Help):
+			inputHandled = true
+			if main.focusedPane == paneMessages {
+				app.showDialog(NewHelpDialog(app.keys.MessagesHelp()))
+			} else {
+				app.showDialog(NewHelpDialog(app.keys.ContextHelp()))
+			}
+		case key.Matches(msg, keys.NextPane):
+			inputHandled = true
+			main.cyclePane(1)
+		case key.Matches(msg, keys.PrevPane):
+			inputHandled = true
+			main.cyclePane(-1)
+		case key.Matches(msg, keys.Zoom):
+			inputHandled = true
+			main.toggleZoom()
+		case key.Matches(msg, keys.GrowSidebar):
+			inputHandled = true
+			main.resizeSidebar(sidebarStep)
+		case key.Matches(msg, keys.ShrinkSidebar):
+			inputHandled = true
+			main.resizeSidebar(-sidebarStep)
+		case key.Matches(msg, keys.Run):
 			var cmd tea.Cmd
 			cmd = app.submitMessage()
 			cmds = append(cmds, cmd)
-		case "shift+j":
+		case key.Matches(msg, keys.ScrollDown):
 			inputHandled = true
 			main.messagesViewport.LineDown(1)
-		case "shift+k":
+		case key.Matches(msg, keys.ScrollUp):
 			inputHandled = true
 			main.messagesViewport.LineUp(1)
-		case "ctrl+n":
+		case key.Matches(msg, keys.NewSession):
 			inputHandled = true
 			app.newSession()
-		case "esc":
+		case key.Matches(msg, keys.Notifications):
+			inputHandled = true
+			app.showDialog(NewNotificationsPanel(app.notifications))
+		case key.Matches(msg, keys.Persona):
 			inputHandled = true
-			app.agent.Cancel()
+			app.showPersonaPicker()
+		case key.Matches(msg, keys.Cancel):
+			inputHandled = true
+			app.cancelRun()
+		case key.Matches(msg, keys.FocusPrompt):
+			inputHandled = true
+			main.focusPane(panePrompt)
+		case key.Matches(msg, keys.Search):
+			inputHandled = true
+			main.startSearch()
+		case key.Matches(msg, keys.CopyReply):
+			inputHandled = true
+			cmds = append(cmds, app.copyReply())
+		case key.Matches(msg, keys.CopyCode):
+			inputHandled = true
+			cmds = append(cmds, app.copyCodeBlock())
+		case key.Matches(msg, keys.SaveCode):
+			inputHandled = true
+			app.saveCodeBlock()
 		}
 	}
 
 	if !inputHandled {
-		cmd := main.contextItemsList.Update(app, msg)
-		cmds = append(cmds, cmd)
+		switch main.focusedPane {
+		case paneMessages:
+			main.updateMessages(app, msg)
+		default:
+			cmd := main.contextItemsList.Update(app, msg)
+			cmds = append(cmds, cmd)
+		}
 	}
 
 	return tea.Batch(cmds...)
 }
 
+// updateMessages scrolls the messages while they have focus.
+func (main *Main) updateMessages(app *App, msg tea.Msg) {
+	keys := app.keys.Messages
+
+	switch msg := msg.(type) {
+	case tea.KeyPressMsg:
+		switch {
+		case key.Matches(msg, keys.Up):
+			main.messagesViewport.LineUp(1)
+		case key.Matches(msg, keys.Down):
+			main.messagesViewport.LineDown(1)
+		case key.Matches(msg, keys.PageUp):
+			main.messagesViewport.ViewUp()
+		case key.Matches(msg, keys.PageDown):
+			main.messagesViewport.ViewDown()
+		case key.Matches(msg, keys.HalfPageUp):
+			main.messagesViewport.HalfViewUp()
+		case key.Matches(msg, keys.HalfPageDown):
+			main.messagesViewport.HalfViewDown()
+		case key.Matches(msg, keys.Top):
+			main.messagesViewport.GotoTop()
+		case key.Matches(msg, keys.Bottom):
+			main.messagesViewport.GotoBottom()
+		case key.Matches(msg, keys.NextMatch):
+			main.showMatch(1)
+		case key.Matches(msg, keys.PrevMatch):
+			main.showMatch(-1)
+		}
+	}
+}
+
+// updateSearch handles messages while the search query is typed. Matches
+// are updated as the query changes.
+func (main *Main) updateSearch(app *App, msg tea.Msg) tea.Cmd {
+	keys := app.keys.Search
+
+	switch msg := msg.(type) {
+	case tea.KeyPressMsg:
+		switch {
+		case key.Matches(msg, keys.Confirm):
+			main.stopSearch(false)
+			return nil
+		case key.Matches(msg, keys.Cancel):
+			main.stopSearch(true)
+			return nil
+		}
+	}
+
+	query := main.search.query()
+
+	var cmd tea.Cmd
+	main.search.input, cmd = main.search.input.Update(msg)
+
+	if main.search.query() != query {
+		main.search.find()
+		main.search.current = main.search.firstMatchFrom(main.messagesViewport.YOffset)
+		main.showMatch(0)
+	}
+
+	return cmd
+}
+
+// updatePrompt handles messages while the prompt has focus. All keys go to
+// the prompt except for the ones leaving it.
+func (main *Main) updatePrompt(app *App, msg tea.Msg) tea.Cmd {
+	keys := app.keys.Prompt
+
+	switch msg := msg.(type) {
+	case tea.KeyPressMsg:
+		switch {
+		case key.Matches(msg, keys.Quit):
+			return tea.Quit
+		case key.Matches(msg, keys.Help):
+			app.showDialog(NewHelpDialog(app.keys.PromptHelp()))
+			return nil
+		case key.Matches(msg, keys.Notifications):
+			app.showDialog(NewNotificationsPanel(app.notifications))
+			return nil
+		case key.Matches(msg, keys.NextPane):
+			main.cyclePane(1)
+			return nil
+		case key.Matches(msg, keys.PrevPane):
+			main.cyclePane(-1)
+			return nil
+		case key.Matches(msg, keys.Zoom):
+			main.toggleZoom()
+			return nil
+		case key.Matches(msg, keys.Leave):
+			main.focusPane(paneContext)
+			return nil
+		}
+	}
+
+	return main.prompt.Update(app, msg)
+}
+
 func (main *Main) View() string {
+	if main.zoomed {
+		switch main.focusedPane {
+		case paneContext:
+			return main.contextItemsListView()
+		case paneMessages:
+			return main.messagesView()
+		case panePrompt:
+			return main.promptView()
+		}
+	}
+
 	sidebar := lipgloss.JoinVertical(lipgloss.Top, main.contextItemsListView())
-	mainpane := main.messagesView()
+	mainpane := lipgloss.JoinVertical(lipgloss.Top, main.messagesView(), main.promptView())
 	return lipgloss.JoinHorizontal(lipgloss.Left, sidebar, mainpane)
 }
 
 func (main *Main) contextItemsListView() string {
 	return util.RenderBorderWithTitle(
 		main.contextItemsList.View(),
-		main.borderIfFocused(),
+		main.borderIfFocused(paneContext),
 		"Context",
-		main.panelTitleStyleIfFocused(),
+		main.panelTitleStyleIfFocused(paneContext),
+	)
+}
+
+func (main *Main) promptView() string {
+	return util.RenderBorderWithTitle(
+		main.prompt.View(),
+		main.borderIfFocused(panePrompt),
+		"Prompt",
+		main.panelTitleStyleIfFocused(panePrompt),
 	)
 }
 
 func (main *Main) messagesView() string {
+	content := main.messagesViewport.View()
+	if main.search.typing {
+		content = lipgloss.JoinVertical(lipgloss.Left, content, main.search.input.View())
+	}
+
+	title := "Messages"
+	if status := main.search.status(); status != "" {
+		title += " (" + status + ")"
+	}
+
 	return util.RenderBorderWithTitle(
-		main.messagesViewport.View(),
-		borderStyle,
-		"Messages",
-		textStyle,
+		content,
+		main.borderIfFocused(paneMessages),
+		title,
+		main.panelTitleStyleIfFocused(paneMessages),
 	)
 }
 
-func (main *Main) borderIfFocused() lipgloss.Style {
-	if main.hasFocus {
+func (main *Main) borderIfFocused(p pane) lipgloss.Style {
+	if main.hasFocus && main.focusedPane == p {
 		return focusedBorderStyle
 	}
 	return borderStyle
 }
 
-func (main *Main) panelTitleStyleIfFocused() lipgloss.Style {
-	if main.hasFocus {
+func (main *Main) panelTitleStyleIfFocused(p pane) lipgloss.Style {
+	if main.hasFocus && main.focusedPane == p {
 		return focusedPanelTitleStyle
 	}
 
@@ -0,0 +1,101 @@
+package app
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea/v2"
+	"github.com/gkampitakis/go-snaps/snaps"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMainPanes(t *testing.T) {
+	t.Parallel()
+
+	t.Run("tab cycles focus", func(t *testing.T) {
+		t.Parallel()
+
+		app := bareApp(t)
+		assert.Equal(t, paneContext, app.main.focusedPane)
+
+		app = update(app, keyPress(tea.KeyTab))
+		assert.Equal(t, paneMessages, app.main.focusedPane)
+		assert.False(t, app.main.contextItemsList.IsFocused())
+		snaps.MatchStandaloneSnapshot(t, app.View())
+
+		app = update(app, keyPress(tea.KeyTab))
+		assert.Equal(t, panePrompt, app.main.focusedPane)
+		assert.True(t, app.main.prompt.IsFocused())
+
+		app = update(app, keyPress(tea.KeyTab))
+		assert.Equal(t, paneContext, app.main.focusedPane)
+
+		app = update(app, keymod(tea.ModShift, tea.KeyTab))
+		assert.Equal(t, panePrompt, app.main.focusedPane)
+	})
+
+	t.Run("scrolling messages", func(t *testing.T) {
+		t.Parallel()
+
+		app := bareApp(t)
+		var reply strings.Builder
+		for i := range 50 {
+			fmt.Fprintf(&reply, "line %d\n\n", i)
+		}
+		app = update(app, streamFinished(reply.String()))
+		app = update(app, keyPress(tea.KeyTab))
+
+		viewport := &app.main.messagesViewport
+		assert.True(t, viewport.AtTop())
+
+		app = update(app, keyPress('j'))
+		assert.Equal(t, 1, viewport.YOffset)
+
+		app = update(app, keyPress(tea.KeyPgDown))
+		assert.Equal(t, 1+viewport.Height(), viewport.YOffset)
+
+		app = update(app, keymod(tea.ModCtrl, 'u'))
+		assert.Equal(t, 1+viewport.Height()-viewport.Height()/2, viewport.YOffset)
+
+		app = update(app, keyPress('G'))
+		assert.True(t, viewport.AtBottom())
+
+		app = update(app, keyPress('g'))
+		assert.True(t, viewport.AtTop())
+	})
+
+	t.Run("zoom", func(t *testing.T) {
+		t.Parallel()
+
+		app := bareApp(t)
+		app = update(app, keyPress('z'))
+		snaps.MatchStandaloneSnapshot(t, app.View())
+
+		// the zoomed pane follows the focus
+		app = update(app, keyPress(tea.KeyTab))
+		assert.Equal(t, 64-2, app.main.messagesViewport.Width())
+		snaps.MatchStandaloneSnapshot(t, app.View())
+
+		app = update(app, keyPress('z'))
+		assert.Equal(t, 64-64/3-2, app.main.messagesViewport.Width())
+	})
+
+	t.Run("resizing the sidebar", func(t *testing.T) {
+		t.Parallel()
+
+		app := bareApp(t)
+		app = update(app, keyPress('>'))
+		assert.Equal(t, 64/3+sidebarStep, app.main.sidebarWidth())
+		snaps.MatchStandaloneSnapshot(t, app.View())
+
+		for range 20 {
+			app = update(app, keyPress('<'))
+		}
+		assert.Equal(t, minPaneWidth, app.main.sidebarWidth())
+
+		// shrinking past the minimum doesn't need to be undone
+		app = update(app, keyPress('>'))
+		assert.Equal(t, minPaneWidth+sidebarStep, app.main.sidebarWidth())
+	})
+}
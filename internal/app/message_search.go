@@ -0,0 +1,163 @@
+package app
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/charmbracelet/bubbles/v2/textinput"
+	"github.com/charmbracelet/lipgloss/v2"
+	"github.com/charmbracelet/x/ansi"
+)
+
+var (
+	searchMatchStyle  = lipgloss.NewStyle().Background(lipgloss.Color("3")).Foreground(lipgloss.Color("0"))
+	currentMatchStyle = lipgloss.NewStyle().Background(lipgloss.Color("5")).Foreground(lipgloss.Color("15"))
+)
+
+// searchMatch is an occurrence of the search query in the rendered
+// messages. Columns are cells, so they don't depend on the styling.
+type searchMatch struct {
+	line  int
+	start int
+	end   int
+}
+
+// messageSearch finds a query in the rendered messages. The query is
+// matched against the text without styling, and matches are highlighted
+// over the styled lines.
+type messageSearch struct {
+	input   textinput.Model
+	typing  bool // the query is being typed
+	lines   []string
+	matches []searchMatch
+	current int // index of the match scrolled to
+}
+
+func newMessageSearch() messageSearch {
+	input := textinput.New()
+	input.Prompt = "/"
+	input.Placeholder = "Search"
+
+	return messageSearch{
+		input: input,
+	}
+}
+
+func (s *messageSearch) query() string {
+	return s.input.Value()
+}
+
+// active checks if there is a query to highlight.
+func (s *messageSearch) active() bool {
+	return s.query() != ""
+}
+
+// setLines sets the rendered lines to search in, keeping the current match
+// when possible.
+func (s *messageSearch) setLines(lines []string) {
+	s.lines = lines
+	s.find()
+	s.current = min(s.current, max(len(s.matches)-1, 0))
+}
+
+// find updates the matches for the query. The search is case insensitive
+// unless the query contains an upper case letter.
+func (s *messageSearch) find() {
+	s.matches = nil
+
+	query := s.query()
+	if query == "" {
+		return
+	}
+
+	ignoreCase := !strings.ContainsFunc(query, unicode.IsUpper)
+	if ignoreCase {
+		query = strings.ToLower(query)
+	}
+
+	for i, line := range s.lines {
+		text := ansi.Strip(line)
+		if ignoreCase {
+			text = strings.ToLower(text)
+		}
+
+		offset := 0
+		for {
+			index := strings.Index(text[offset:], query)
+			if index < 0 {
+				break
+			}
+
+			start := offset + index
+			end := start + len(query)
+			s.matches = append(s.matches, searchMatch{
+				line:  i,
+				start: ansi.StringWidth(text[:start]),
+				end:   ansi.StringWidth(text[:end]),
+			})
+			offset = end
+		}
+	}
+}
+
+// firstMatchFrom returns the index of the first match at or after line,
+// wrapping around to the first match.
+func (s *messageSearch) firstMatchFrom(line int) int {
+	for i, match := range s.matches {
+		if match.line >= line {
+			return i
+		}
+	}
+	return 0
+}
+
+// move changes the current match by delta, wrapping around.
+func (s *messageSearch) move(delta int) {
+	if len(s.matches) == 0 {
+		return
+	}
+
+	s.current = (s.current + delta + len(s.matches)) % len(s.matches)
+}
+
+func (s *messageSearch) currentMatch() (searchMatch, bool) {
+	if s.current >= len(s.matches) {
+		return searchMatch{}, false
+	}
+	return s.matches[s.current], true
+}
+
+// highlighted returns the lines with the matches highlighted.
+func (s *messageSearch) highlighted() []string {
+	if len(s.matches) == 0 {
+		return s.lines
+	}
+
+	ranges := map[int][]lipgloss.Range{}
+	for i, match := range s.matches {
+		style := searchMatchStyle
+		if i == s.current {
+			style = currentMatchStyle
+		}
+		ranges[match.line] = append(ranges[match.line], lipgloss.NewRange(match.start, match.end, style))
+	}
+
+	lines := make([]string, len(s.lines))
+	for i, line := range s.lines {
+		lines[i] = lipgloss.StyleRanges(line, ranges[i]...)
+	}
+
+	return lines
+}
+
+// status describes the position in the matches, for the panel title.
+func (s *messageSearch) status() string {
+	if !s.active() {
+		return ""
+	}
+	if len(s.matches) == 0 {
+		return "no matches"
+	}
+	return fmt.Sprintf("%d/%d", s.current+1, len(s.matches))
+}
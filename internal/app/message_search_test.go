@@ -0,0 +1,115 @@
+package app
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea/v2"
+	"github.com/charmbracelet/lipgloss/v2"
+	"github.com/charmbracelet/x/ansi"
+	"github.com/gkampitakis/go-snaps/snaps"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMessageSearch(t *testing.T) {
+	t.Parallel()
+
+	styled := lipgloss.NewStyle().Bold(true).Render
+
+	newSearch := func(query string, lines ...string) *messageSearch {
+		s := newMessageSearch()
+		s.input.SetValue(query)
+		s.setLines(lines)
+		return &s
+	}
+
+	t.Run("finds matches ignoring styling", func(t *testing.T) {
+		t.Parallel()
+
+		s := newSearch("foo", "a "+styled("fo")+"o foo", "bar", "→ foo")
+		assert.Equal(t, []searchMatch{
+			{line: 0, start: 2, end: 5},
+			{line: 0, start: 6, end: 9},
+			{line: 2, start: 2, end: 5},
+		}, s.matches)
+	})
+
+	t.Run("is case sensitive with upper case letters", func(t *testing.T) {
+		t.Parallel()
+
+		assert.Len(t, newSearch("foo", "Foo foo").matches, 2)
+		assert.Len(t, newSearch("Foo", "Foo foo").matches, 1)
+	})
+
+	t.Run("highlights keep the text", func(t *testing.T) {
+		t.Parallel()
+
+		s := newSearch("foo", styled("a foo b"), "foo")
+		highlighted := s.highlighted()
+
+		assert.Equal(t, "a foo b", ansi.Strip(highlighted[0]))
+		assert.Equal(t, "foo", ansi.Strip(highlighted[1]))
+		assert.Contains(t, highlighted[0], currentMatchStyle.Render("foo"))
+		assert.Contains(t, highlighted[1], searchMatchStyle.Render("foo"))
+	})
+
+	t.Run("status", func(t *testing.T) {
+		t.Parallel()
+
+		assert.Equal(t, "", newSearch("", "foo").status())
+		assert.Equal(t, "no matches", newSearch("bar", "foo").status())
+
+		s := newSearch("foo", "foo foo")
+		s.move(1)
+		assert.Equal(t, "2/2", s.status())
+		s.move(1)
+		assert.Equal(t, "1/2", s.status())
+		s.move(-1)
+		assert.Equal(t, "2/2", s.status())
+	})
+
+	t.Run("in messages", func(t *testing.T) {
+		t.Parallel()
+
+		var reply strings.Builder
+		for i := range 30 {
+			fmt.Fprintf(&reply, "line %d\n\n", i)
+		}
+
+		app := bareApp(t)
+		app = update(app, streamFinished(reply.String()))
+
+		app = update(app, keyPress('/'))
+		assert.Equal(t, paneMessages, app.main.focusedPane)
+		app = typeText(app, "line 2")
+
+		// matches "line 2" and "line 20" to "line 29"
+		assert.Equal(t, "1/11", app.main.search.status())
+		snaps.MatchStandaloneSnapshot(t, app.View())
+
+		app = update(app, keyPress(tea.KeyEnter))
+		assert.False(t, app.main.search.typing)
+
+		app = update(app, keyPress('n'))
+		assert.Equal(t, "2/11", app.main.search.status())
+
+		app = update(app, keyPress('N'))
+		app = update(app, keyPress('N'))
+		assert.Equal(t, "11/11", app.main.search.status())
+		match, ok := app.main.search.currentMatch()
+		require.True(t, ok)
+		assert.GreaterOrEqual(t, match.line, app.main.messagesViewport.YOffset)
+		assert.Less(t, match.line, app.main.messagesViewport.YOffset+app.main.messagesViewport.Height())
+		snaps.MatchStandaloneSnapshot(t, app.View())
+
+		// matches survive rendering
+		app = update(app, streamFinished(reply.String()))
+		assert.Equal(t, "11/11", app.main.search.status())
+
+		app = update(app, keyPress('/'))
+		app = update(app, keyPress(tea.KeyEscape))
+		assert.Equal(t, "", app.main.search.status())
+	})
+}
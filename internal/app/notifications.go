@@ -0,0 +1,158 @@
+package app
+
+import (
+	"strings"
+	"time"
+
+	"mark/internal/util"
+
+	tea "github.com/charmbracelet/bubbletea/v2"
+	"github.com/charmbracelet/lipgloss/v2"
+	"github.com/charmbracelet/x/ansi"
+)
+
+// maxNotifications is the number of notifications kept in the history. The
+// oldest ones are dropped first.
+const maxNotifications = 500
+
+// toastDuration is how long a toast stays on screen.
+const toastDuration = 3 * time.Second
+
+type notificationLevel int
+
+const (
+	levelInfo notificationLevel = iota
+	levelWarning
+	levelError
+)
+
+var (
+	warningStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("3"))
+	errorStyle   = lipgloss.NewStyle().Foreground(lipgloss.Color("1"))
+	toastStyle   = borderStyle.BorderForeground(lipgloss.Color("8"))
+)
+
+// icon returns the symbol shown before the notifications of the level.
+func (level notificationLevel) icon() string {
+	switch level {
+	case levelWarning:
+		return warningStyle.Render("!")
+	case levelError:
+		return errorStyle.Render("✗")
+	default:
+		return hintStyle.Render("·")
+	}
+}
+
+// notification is an event worth telling the user about, like an error or
+// the end of a run.
+type notification struct {
+	time  time.Time
+	level notificationLevel
+	text  string
+}
+
+// toastExpired hides the toast with the ID, unless a newer one replaced it.
+type toastExpired int
+
+// Notifications is the history of the notifications of the App and the toast
+// currently shown, if any.
+type Notifications struct {
+	entries []notification
+	toast   *notification
+	toasts  int // number of toasts shown, the ID of the last one
+
+	now func() time.Time // replaced in tests
+}
+
+func NewNotifications() *Notifications {
+	return &Notifications{now: time.Now}
+}
+
+// add records a notification.
+func (n *Notifications) add(level notificationLevel, text string) notification {
+	entry := notification{time: n.now(), level: level, text: text}
+
+	n.entries = append(n.entries, entry)
+	if len(n.entries) > maxNotifications {
+		n.entries = n.entries[len(n.entries)-maxNotifications:]
+	}
+
+	return entry
+}
+
+// addToast records a notification and shows it in a toast, which doesn't
+// take the focus and hides itself. Returns the command hiding it.
+func (n *Notifications) addToast(level notificationLevel, text string) tea.Cmd {
+	entry := n.add(level, text)
+	n.toast = &entry
+	n.toasts++
+
+	id := toastExpired(n.toasts)
+	return tea.Tick(toastDuration, func(time.Time) tea.Msg { return id })
+}
+
+func (n *Notifications) Update(app *App, msg tea.Msg) tea.Cmd {
+	switch msg := msg.(type) {
+	case toastExpired:
+		if int(msg) == n.toasts {
+			n.toast = nil
+		}
+	}
+
+	return nil
+}
+
+// toastView renders the toast, empty when there is none.
+func (n *Notifications) toastView(width int) string {
+	if n.toast == nil {
+		return ""
+	}
+
+	text := n.toast.level.icon() + " " + n.toast.text
+	text = ansi.Truncate(strings.ReplaceAll(text, "\n", " "), max(width-2, 1), "…") // borders
+
+	return toastStyle.Render(text)
+}
+
+// notify records a notification shown only in the history.
+func (m *App) notify(level notificationLevel, text string) {
+	m.notifications.add(level, text)
+}
+
+// toast records a notification and shows it in a toast.
+func (m *App) toast(level notificationLevel, text string) tea.Cmd {
+	return m.notifications.addToast(level, text)
+}
+
+// placeToast draws the toast over the bottom right corner of view, above the
+// status bar.
+func (m *App) placeToast(view string) string {
+	toast := m.notifications.toastView(m.width / 2)
+	if toast == "" {
+		return view
+	}
+
+	x := m.width - lipgloss.Width(toast)
+	y := m.height - 1 - lipgloss.Height(toast)
+	return util.PlaceOverlay(x, y, toast, view)
+}
+
+// remoteCommandText describes the messages sent by remote commands. Returns
+// false for other messages, like the input of attached clients.
+func remoteCommandText(msg tea.Msg) (string, bool) {
+	switch msg := msg.(type) {
+	case AddContextItemTextMsg:
+		return "Remote: add text to the context", true
+	case AddContextItemFileMsg:
+		return "Remote: add " + string(msg) + " to the context", true
+	case RunMsg:
+		return "Remote: run the agent", true
+	case NewSessionMsg:
+		return "Remote: new session", true
+	case ReplyQueryMsg:
+		return "Remote: read the reply", true
+	default:
+		return "", false
+	}
+}
@@ -0,0 +1,103 @@
+package app
+
+import (
+	"fmt"
+	"strings"
+
+	"mark/internal/util"
+
+	"github.com/charmbracelet/bubbles/v2/key"
+	tea "github.com/charmbracelet/bubbletea/v2"
+	"github.com/charmbracelet/lipgloss/v2"
+	"github.com/charmbracelet/x/ansi"
+)
+
+// notificationsRows is the maximum number of notifications shown at once.
+const notificationsRows = 16
+
+// NotificationsPanel lists the history of notifications, the newest last.
+// It starts scrolled to the bottom and follows new notifications until it's
+// scrolled up.
+type NotificationsPanel struct {
+	width         int
+	rows          int // number of notifications shown at once
+	notifications *Notifications
+	offset        int  // index of the first visible notification
+	follow        bool // keep the newest notification visible
+}
+
+func NewNotificationsPanel(notifications *Notifications) *NotificationsPanel {
+	return &NotificationsPanel{
+		rows:          notificationsRows,
+		notifications: notifications,
+		follow:        true,
+	}
+}
+
+func (panel *NotificationsPanel) Focus() {}
+
+func (panel *NotificationsPanel) Blur() {}
+
+func (panel *NotificationsPanel) SetSize(width, height int) {
+	panel.width = width
+	panel.rows = util.Clamp(height-2, 1, notificationsRows) // borders
+}
+
+func (panel *NotificationsPanel) maxOffset() int {
+	return max(len(panel.notifications.entries)-panel.rows, 0)
+}
+
+func (panel *NotificationsPanel) Update(app *App, msg tea.Msg) tea.Cmd {
+	switch msg := msg.(type) {
+	case tea.KeyPressMsg:
+		keys := app.keys.Notifications
+		offset := panel.firstVisible()
+
+		switch {
+		case key.Matches(msg, keys.Close, app.keys.Main.Notifications, app.keys.Prompt.Notifications):
+			// the keys showing the panel also hide it
+			app.hideDialog()
+		case key.Matches(msg, keys.Up):
+			panel.offset = util.Clamp(offset-1, 0, panel.maxOffset())
+			panel.follow = false
+		case key.Matches(msg, keys.Down):
+			panel.offset = util.Clamp(offset+1, 0, panel.maxOffset())
+			panel.follow = panel.offset == panel.maxOffset()
+		}
+	}
+
+	return nil
+}
+
+// firstVisible returns the index of the first visible notification.
+func (panel *NotificationsPanel) firstVisible() int {
+	if panel.follow {
+		return panel.maxOffset()
+	}
+	return util.Clamp(panel.offset, 0, panel.maxOffset())
+}
+
+func (panel *NotificationsPanel) View() string {
+	width := max(panel.width-2, 0) // borders
+	entries := panel.notifications.entries
+
+	var lines []string
+	offset := panel.firstVisible()
+	for _, entry := range entries[offset:min(offset+panel.rows, len(entries))] {
+		text := strings.ReplaceAll(entry.text, "\n", " ")
+		line := hintStyle.Render(entry.time.Format("15:04:05")) + " " + entry.level.icon() + " " + text
+		lines = append(lines, ansi.Truncate(line, width, "…"))
+	}
+	if len(entries) == 0 {
+		lines = append(lines, hintStyle.Render("No notifications."))
+	}
+
+	title := "Notifications"
+	if len(entries) > panel.rows {
+		title += fmt.Sprintf(" (%d-%d of %d)", offset+1, offset+len(lines), len(entries))
+	}
+
+	content := lipgloss.NewStyle().Width(width).Render(strings.Join(lines, "\n"))
+
+	return util.RenderBorderWithTitle(content, focusedBorderStyle, title, focusedPanelTitleStyle)
+}
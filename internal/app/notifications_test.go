@@ -0,0 +1,134 @@
+package app
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"mark/internal/llm/provider"
+
+	tea "github.com/charmbracelet/bubbletea/v2"
+	"github.com/gkampitakis/go-snaps/snaps"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// notificationTexts returns the texts of the notifications of app.
+func notificationTexts(app App) []string {
+	var texts []string
+	for _, entry := range app.notifications.entries {
+		texts = append(texts, entry.text)
+	}
+	return texts
+}
+
+func TestNotifications(t *testing.T) {
+	t.Parallel()
+
+	t.Run("records the events of runs", func(t *testing.T) {
+		t.Parallel()
+
+		app := bareApp(t)
+		app = update(app, RunMsg{})
+		app = update(app, streamMsg{run: app.run, msg: streamRetrying{Attempt: 2, MaxAttempts: 4, Delay: time.Second, Err: &provider.APIError{StatusCode: http.StatusTooManyRequests, Err: fmt.Errorf("slow down")}}})
+		app = update(app, streamMsg{run: app.run, msg: streamFinished("reply")})
+		app = update(app, RunMsg{})
+		app = update(app, keyPress(tea.KeyEscape))
+		app = update(app, ErrMsg{Err: fmt.Errorf("boom")})
+
+		assert.Equal(t, []string{
+			"Run 1 started",
+			"Retrying in 1s (2/4): slow down",
+			"Run finished in 0s",
+			"Run 2 started",
+			"Run cancelled",
+			"boom",
+		}, notificationTexts(app))
+		assert.Equal(t, levelWarning, app.notifications.entries[1].level)
+		assert.Equal(t, levelError, app.notifications.entries[5].level)
+	})
+
+	t.Run("records remote commands", func(t *testing.T) {
+		t.Parallel()
+
+		app := bareApp(t)
+		app = update(app, eventMsg{AddContextItemTextMsg("notes")})
+		app = update(app, eventMsg{AddContextItemFileMsg("main.go")})
+		app = update(app, eventMsg{keyPress('x')}) // input of an attached client
+
+		assert.Equal(t, []string{
+			"Remote: add text to the context",
+			"Remote: add main.go to the context",
+		}, notificationTexts(app))
+	})
+
+	t.Run("keeps the latest notifications", func(t *testing.T) {
+		t.Parallel()
+
+		notifications := NewNotifications()
+		for i := range maxNotifications + 10 {
+			notifications.add(levelInfo, fmt.Sprint(i))
+		}
+
+		require.Len(t, notifications.entries, maxNotifications)
+		assert.Equal(t, "10", notifications.entries[0].text)
+	})
+
+	t.Run("toasts hide themselves", func(t *testing.T) {
+		t.Parallel()
+
+		app := bareApp(t)
+		model, cmd := app.Update(eventMsg{RunMsg{}})
+		app = model.(App)
+		require.NotNil(t, cmd)
+		snaps.MatchStandaloneSnapshot(t, render(t, app))
+
+		first := toastExpired(app.notifications.toasts)
+		app = update(app, eventMsg{NewSessionMsg{}})
+		app = update(app, first)
+		assert.Contains(t, render(t, app), "Remote: new session", "an expired toast hides a newer one")
+
+		app = update(app, toastExpired(app.notifications.toasts))
+		assert.NotContains(t, render(t, app), "Remote:")
+	})
+
+	t.Run("panel", func(t *testing.T) {
+		t.Parallel()
+
+		app := bareApp(t)
+		for i := range 20 {
+			app.notify(levelInfo, fmt.Sprintf("event %d", i))
+		}
+		app.notify(levelError, "the last one")
+
+		app = update(app, keyPress('L'))
+		require.IsType(t, &NotificationsPanel{}, app.dialog)
+		v := render(t, app)
+		snaps.MatchStandaloneSnapshot(t, v)
+		assert.Contains(t, v, "the last one")
+
+		app = update(app, keyPress('k'))
+		app.notify(levelInfo, "while scrolled up")
+		assert.NotContains(t, render(t, app), "the last one")
+
+		// back to the bottom, one more line down since a notification was added
+		app = update(app, keyPress('j'))
+		app = update(app, keyPress('j'))
+		app.notify(levelInfo, "followed")
+		assert.Contains(t, render(t, app), "followed")
+
+		app = update(app, keyPress('L'))
+		assert.Nil(t, app.dialog)
+	})
+
+	t.Run("empty panel", func(t *testing.T) {
+		t.Parallel()
+
+		app := bareApp(t)
+		app = update(app, keyPress('L'))
+
+		assert.True(t, strings.Contains(render(t, app), "No notifications."))
+	})
+}
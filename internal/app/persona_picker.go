@@ -0,0 +1,36 @@
+package app
+
+import (
+	"slices"
+
+	"mark/internal/persona"
+
+	tea "github.com/charmbracelet/bubbletea/v2"
+)
+
+// PersonaPicker is a dialog to choose the persona of the session. The first
+// entry selects no persona, sending the configured system prompt.
+type PersonaPicker = ChoicePicker[persona.Persona]
+
+func NewPersonaPicker(none persona.Persona, personas []persona.Persona, selected string, keys ChoiceKeys, callback func(app *App, p persona.Persona) tea.Cmd) *PersonaPicker {
+	entries := append([]persona.Persona{none}, personas...)
+	columns := func(_ int, entry persona.Persona) (string, string) {
+		return personaName(entry), hintStyle.Render(entry.Summary())
+	}
+	p := newChoicePicker("Persona", entries, keys, columns, callback)
+
+	// start on the persona of the session
+	if selected != "" {
+		p.list.moveCursor(max(slices.IndexFunc(entries, func(entry persona.Persona) bool { return entry.Name == selected }), 0))
+	}
+
+	return p
+}
+
+// personaName returns the name shown for p.
+func personaName(p persona.Persona) string {
+	if p.Name == "" {
+		return "(none)"
+	}
+	return p.Name
+}
@@ -0,0 +1,94 @@
+package app
+
+import (
+	"testing"
+
+	"mark/internal/llm"
+	"mark/internal/persona"
+
+	tea "github.com/charmbracelet/bubbletea/v2"
+	"github.com/gkampitakis/go-snaps/snaps"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testPersonas = []persona.Persona{
+	{Name: "go-expert", Prompt: "You know Go.\nAnswer with idiomatic code.", Path: "go-expert.md"},
+	{Name: "reviewer", Prompt: "Review the code.", Path: "reviewer.md"},
+}
+
+// personaApp returns an App with testPersonas, selecting selected.
+func personaApp(t *testing.T, selected string) App {
+	t.Helper()
+
+	app := bareApp(t)
+	require.NoError(t, app.SetPersonas(testPersonas, "Be concise.", selected))
+	return app
+}
+
+func TestPersonas(t *testing.T) {
+	t.Parallel()
+
+	t.Run("the system prompt comes first", func(t *testing.T) {
+		t.Parallel()
+
+		app := personaApp(t, "")
+		app.session.SetPrompt("hello")
+		assert.Equal(t, []llm.Message{
+			{Role: llm.RoleSystem, Content: "Be concise."},
+			{Role: llm.RoleUser, Content: "hello"},
+		}, convertSessionToMessages(app.session))
+
+		app = personaApp(t, "reviewer")
+		app.session.SetPrompt("hello")
+		assert.Equal(t, llm.Message{Role: llm.RoleSystem, Content: "Review the code."}, convertSessionToMessages(app.session)[0])
+	})
+
+	t.Run("no system prompt", func(t *testing.T) {
+		t.Parallel()
+
+		app := bareApp(t)
+		app.session.SetPrompt("hello")
+		assert.Equal(t, []llm.Message{{Role: llm.RoleUser, Content: "hello"}}, convertSessionToMessages(app.session))
+	})
+
+	t.Run("unknown persona", func(t *testing.T) {
+		t.Parallel()
+
+		app := bareApp(t)
+		assert.EqualError(t, app.SetPersonas(testPersonas, "", "poet"), `unknown persona "poet"`)
+	})
+
+	t.Run("choose the persona of the session", func(t *testing.T) {
+		t.Parallel()
+
+		app := personaApp(t, "reviewer")
+		app = update(app, keyPress('p'))
+		require.IsType(t, &PersonaPicker{}, app.dialog)
+		snaps.MatchStandaloneSnapshot(t, render(t, app))
+
+		app = update(app, keyPress('k'))
+		app = update(app, keyPress(tea.KeyEnter))
+
+		assert.Nil(t, app.dialog)
+		assert.Equal(t, "go-expert", app.session.Persona().Name)
+		assert.Contains(t, render(t, app), "persona go-expert")
+
+		// new sessions start with the selected persona
+		app = update(app, NewSessionMsg{})
+		assert.Equal(t, "reviewer", app.session.Persona().Name)
+	})
+
+	t.Run("choose no persona", func(t *testing.T) {
+		t.Parallel()
+
+		app := personaApp(t, "reviewer")
+		app = update(app, keyPress('p'))
+		app = update(app, keyPress('k'))
+		app = update(app, keyPress('k'))
+		app = update(app, keyPress(tea.KeyEnter))
+
+		assert.Equal(t, persona.Persona{Prompt: "Be concise."}, app.session.Persona())
+		assert.NotContains(t, render(t, app), "persona ")
+	})
+}
@@ -0,0 +1,148 @@
+package app
+
+import (
+	"strings"
+
+	"github.com/charmbracelet/bubbles/v2/key"
+	"github.com/charmbracelet/bubbles/v2/textarea"
+	tea "github.com/charmbracelet/bubbletea/v2"
+	"github.com/charmbracelet/lipgloss/v2"
+)
+
+// PromptInput is where the question sent to the agent is composed. Previous
+// prompts can be recalled with up and down from the first and last lines.
+type PromptInput struct {
+	textarea textarea.Model
+
+	history      []string
+	historyIndex int    // position in history, len(history) is the draft
+	draft        string // prompt being composed before browsing history
+}
+
+func NewPromptInput() *PromptInput {
+	input := textarea.New()
+	input.Prompt = ""
+	input.ShowLineNumbers = false
+	input.CharLimit = 0
+	input.MaxHeight = 0
+	input.Styles.Focused.CursorLine = lipgloss.NewStyle()
+	input.Blur()
+
+	return &PromptInput{
+		textarea: input,
+	}
+}
+
+// SetKeyMap shows the key sending the prompt in the placeholder.
+func (p *PromptInput) SetKeyMap(keys PromptKeys) {
+	p.textarea.Placeholder = "Ask a question"
+	if keys.Submit.Enabled() {
+		p.textarea.Placeholder += " (" + keys.Submit.Help().Key + " to send)"
+	}
+}
+
+func (p *PromptInput) Focus() {
+	p.textarea.Focus()
+}
+
+func (p *PromptInput) Blur() {
+	p.textarea.Blur()
+}
+
+func (p *PromptInput) IsFocused() bool {
+	return p.textarea.Focused()
+}
+
+func (p *PromptInput) SetSize(width, height int) {
+	p.textarea.SetWidth(width)
+	p.textarea.SetHeight(height)
+}
+
+func (p *PromptInput) Value() string {
+	return p.textarea.Value()
+}
+
+func (p *PromptInput) SetValue(value string) {
+	p.textarea.SetValue(value)
+}
+
+func (p *PromptInput) Update(app *App, msg tea.Msg) tea.Cmd {
+	switch msg := msg.(type) {
+	case tea.KeyPressMsg:
+		keys := app.keys.Prompt
+
+		switch {
+		case key.Matches(msg, keys.Submit):
+			return p.submit(app)
+		case key.Matches(msg, keys.Editor):
+			return editText(p.textarea.Value(), func(app *App, v string) {
+				app.main.prompt.SetValue(v)
+			})
+		case key.Matches(msg, keys.HistoryPrev):
+			if p.onFirstRow() && p.recall(-1) {
+				return nil
+			}
+		case key.Matches(msg, keys.HistoryNext):
+			if p.onLastRow() && p.recall(1) {
+				return nil
+			}
+		}
+	}
+
+	var cmd tea.Cmd
+	p.textarea, cmd = p.textarea.Update(msg)
+	return cmd
+}
+
+func (p *PromptInput) View() string {
+	return p.textarea.View()
+}
+
+// submit sends the prompt to the agent, adding it to the history.
+func (p *PromptInput) submit(app *App) tea.Cmd {
+	prompt := strings.TrimSpace(p.textarea.Value())
+	if prompt == "" {
+		return nil // nothing to ask
+	}
+
+	if len(p.history) == 0 || p.history[len(p.history)-1] != prompt {
+		p.history = append(p.history, prompt)
+	}
+	p.historyIndex = len(p.history)
+	p.draft = ""
+	p.textarea.Reset()
+
+	return app.submitPrompt(prompt)
+}
+
+// recall replaces the value with the history entry delta positions away
+// from the current one. Returns false if there's no such entry.
+func (p *PromptInput) recall(delta int) bool {
+	index := p.historyIndex + delta
+	if index < 0 || index > len(p.history) {
+		return false
+	}
+
+	// keep what was being composed to come back to it
+	if p.historyIndex == len(p.history) {
+		p.draft = p.textarea.Value()
+	}
+
+	p.historyIndex = index
+	if index == len(p.history) {
+		p.textarea.SetValue(p.draft)
+	} else {
+		p.textarea.SetValue(p.history[index])
+	}
+
+	return true
+}
+
+func (p *PromptInput) onFirstRow() bool {
+	return p.textarea.Line() == 0 && p.textarea.LineInfo().RowOffset == 0
+}
+
+func (p *PromptInput) onLastRow() bool {
+	info := p.textarea.LineInfo()
+	return p.textarea.Line() == p.textarea.LineCount()-1 && info.RowOffset == info.Height-1
+}
@@ -0,0 +1,125 @@
+package app
+
+import (
+	"testing"
+
+	"mark/internal/domain"
+	"mark/internal/llm"
+
+	tea "github.com/charmbracelet/bubbletea/v2"
+	"github.com/gkampitakis/go-snaps/snaps"
+	"github.com/stretchr/testify/assert"
+)
+
+func typeText(app App, text string) App {
+	for _, r := range text {
+		app = update(app, keyPress(r))
+	}
+	return app
+}
+
+func TestPromptInput(t *testing.T) {
+	t.Parallel()
+
+	t.Run("focus", func(t *testing.T) {
+		t.Parallel()
+
+		app := bareApp(t)
+		app = update(app, keyPress('i'))
+		assert.True(t, app.main.prompt.IsFocused())
+		assert.False(t, app.main.contextItemsList.IsFocused())
+
+		app = update(app, keyPress(tea.KeyEscape))
+		assert.False(t, app.main.prompt.IsFocused())
+		assert.True(t, app.main.contextItemsList.IsFocused())
+	})
+
+	t.Run("typing", func(t *testing.T) {
+		t.Parallel()
+
+		app := bareApp(t)
+		app = update(app, keyPress('i'))
+		app = typeText(app, "first line")
+		app = update(app, keyPress(tea.KeyEnter))
+		app = typeText(app, "second line")
+
+		assert.Equal(t, "first line\nsecond line", app.main.prompt.Value())
+		assert.Empty(t, app.session.Prompt())
+
+		snaps.MatchStandaloneSnapshot(t, app.View())
+	})
+
+	t.Run("submit", func(t *testing.T) {
+		t.Parallel()
+
+		app := bareApp(t)
+		app = update(app, keyPress('i'))
+		app = typeText(app, "what is this?")
+
+		model, cmd := app.Update(keymod(tea.ModCtrl, tea.KeyEnter))
+		app = model.(App)
+
+		assert.NotNil(t, cmd)
+		assert.Equal(t, "what is this?", app.session.Prompt())
+		assert.Equal(t, "", app.main.prompt.Value())
+	})
+
+	t.Run("an empty submit starts no run", func(t *testing.T) {
+		t.Parallel()
+
+		app := bareApp(t)
+		app = update(app, keyPress('i'))
+		app = typeText(app, "  ")
+
+		model, cmd := app.Update(keymod(tea.ModCtrl, tea.KeyEnter))
+		app = model.(App)
+
+		assert.Nil(t, cmd)
+		assert.False(t, app.running)
+		assert.Equal(t, runID(0), app.run)
+		assert.Equal(t, "  ", app.main.prompt.Value())
+	})
+
+	t.Run("history", func(t *testing.T) {
+		t.Parallel()
+
+		app := bareApp(t)
+		app = update(app, keyPress('i'))
+		app = typeText(app, "first")
+		app = update(app, keymod(tea.ModCtrl, tea.KeyEnter))
+		app = typeText(app, "second")
+		app = update(app, keymod(tea.ModCtrl, tea.KeyEnter))
+		app = typeText(app, "draft")
+
+		app = update(app, keyPress(tea.KeyUp))
+		assert.Equal(t, "second", app.main.prompt.Value())
+
+		app = update(app, keyPress(tea.KeyUp))
+		assert.Equal(t, "first", app.main.prompt.Value())
+
+		// there's nothing before the first entry
+		app = update(app, keyPress(tea.KeyUp))
+		assert.Equal(t, "first", app.main.prompt.Value())
+
+		app = update(app, keyPress(tea.KeyDown))
+		assert.Equal(t, "second", app.main.prompt.Value())
+
+		app = update(app, keyPress(tea.KeyDown))
+		assert.Equal(t, "draft", app.main.prompt.Value())
+	})
+
+	t.Run("messages keep the prompt separate from the context", func(t *testing.T) {
+		t.Parallel()
+
+		session := domain.MakeSession()
+		session.Context().AddItem(domain.TextItem("some context"))
+		session.SetPrompt("the question")
+
+		messages := convertSessionToMessages(session)
+
+		assert.Equal(t, []llm.Message{
+			{Role: llm.RoleUser, Content: "some context\n\n"},
+			{Role: llm.RoleUser, Content: "the question"},
+		}, messages)
+	})
+}
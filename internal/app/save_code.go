@@ -0,0 +1,146 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"mark/internal/markdown"
+
+	tea "github.com/charmbracelet/bubbletea/v2"
+	"github.com/charmbracelet/lipgloss/v2"
+	"github.com/pmezard/go-difflib/difflib"
+)
+
+var (
+	diffAddedStyle   = lipgloss.NewStyle().Foreground(lipgloss.Color("2"))
+	diffRemovedStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("1"))
+	diffHunkStyle    = lipgloss.NewStyle().Foreground(lipgloss.Color("6"))
+)
+
+// saveCodeBlock writes a code block of the reply to a file, asking which one
+// when there are several.
+func (m *App) saveCodeBlock() {
+	blocks := markdown.CodeBlocks(m.session.Reply())
+
+	switch len(blocks) {
+	case 0:
+		return
+	case 1:
+		m.askSavePath(blocks[0])
+		return
+	}
+
+	m.showDialog(NewCodeBlockPicker("Save code block", blocks, m.keys.CodeBlocks, func(app *App, block markdown.CodeBlock) tea.Cmd {
+		app.askSavePath(block)
+		return nil
+	}))
+}
+
+// askSavePath asks where to save block, suggesting the filename hinted in
+// the reply.
+func (m *App) askSavePath(block markdown.CodeBlock) {
+	dialog := NewInputDialog(func(app *App, path string) error {
+		return app.saveCodeBlockTo(block, path)
+	})
+	dialog.SetTitle("Save to")
+	dialog.SetValue(block.Filename)
+
+	m.showDialog(dialog)
+}
+
+// saveCodeBlockTo writes block to path, relative to the working directory.
+// An existing file is only replaced after confirming the diff.
+func (m *App) saveCodeBlockTo(block markdown.CodeBlock, path string) error {
+	path = strings.TrimSpace(path)
+	if path == "" {
+		return errors.New("no path to save the code block to")
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(m.cwd, path)
+	}
+
+	current, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return m.writeCodeBlock(block, path)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	if string(current) == block.Code {
+		m.logger.Info("File is unchanged", slog.String("path", path))
+		return nil
+	}
+
+	name := m.displayPath(path)
+	diff, err := unifiedDiff(name, string(current), block.Code)
+	if err != nil {
+		return err
+	}
+
+	m.showDialog(NewConfirmDialog("Overwrite "+name+"?", diff, m.keys.Confirm, func(app *App) tea.Cmd {
+		if err := app.writeCodeBlock(block, path); err != nil {
+			app.handleError(err)
+		}
+		return nil
+	}))
+
+	return nil
+}
+
+func (m *App) writeCodeBlock(block markdown.CodeBlock, path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("failed to create directory for %s: %w", path, err)
+	}
+
+	if err := os.WriteFile(path, []byte(block.Code), 0o644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+
+	m.logger.Info("Saved code block", slog.String("path", path))
+	return nil
+}
+
+// displayPath returns path relative to the working directory when it's
+// inside of it.
+func (m *App) displayPath(path string) string {
+	rel, err := filepath.Rel(m.cwd, path)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return path
+	}
+	return rel
+}
+
+// unifiedDiff returns the colored unified diff from current to updated.
+func unifiedDiff(name string, current string, updated string) (string, error) {
+	diff, err := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        difflib.SplitLines(current),
+		B:        difflib.SplitLines(updated),
+		FromFile: name,
+		ToFile:   name + " (reply)",
+		Context:  3,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to diff %s: %w", name, err)
+	}
+
+	lines := strings.Split(strings.TrimSuffix(diff, "\n"), "\n")
+	for i, line := range lines {
+		switch {
+		case strings.HasPrefix(line, "+++"), strings.HasPrefix(line, "---"):
+			// file names
+		case strings.HasPrefix(line, "+"):
+			lines[i] = diffAddedStyle.Render(line)
+		case strings.HasPrefix(line, "-"):
+			lines[i] = diffRemovedStyle.Render(line)
+		case strings.HasPrefix(line, "@@"):
+			lines[i] = diffHunkStyle.Render(line)
+		}
+	}
+
+	return strings.Join(lines, "\n"), nil
+}
@@ -0,0 +1,103 @@
+package app
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea/v2"
+	"github.com/gkampitakis/go-snaps/snaps"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSaveCodeBlock(t *testing.T) {
+	t.Parallel()
+
+	reply := "Create `cmd/main.go`:\n\n```go\npackage main\n\nfunc main() {}\n```\n"
+
+	t.Run("new file", func(t *testing.T) {
+		t.Parallel()
+
+		app := bareApp(t)
+		app = update(app, streamFinished(reply))
+
+		app = update(app, keyPress('s'))
+		dialog, ok := app.dialog.(*InputDialog)
+		require.True(t, ok)
+		assert.Equal(t, "cmd/main.go", dialog.input.Value())
+		snaps.MatchStandaloneSnapshot(t, app.View())
+
+		app = update(app, keyPress(tea.KeyEnter))
+		assert.Nil(t, app.dialog)
+
+		content, err := os.ReadFile(filepath.Join(app.cwd, "cmd", "main.go"))
+		require.NoError(t, err)
+		assert.Equal(t, "package main\n\nfunc main() {}\n", string(content))
+	})
+
+	t.Run("existing file", func(t *testing.T) {
+		t.Parallel()
+
+		app := bareApp(t)
+		path := filepath.Join(app.cwd, "cmd", "main.go")
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.NoError(t, os.WriteFile(path, []byte("package main\n"), 0o644))
+
+		app = update(app, streamFinished(reply))
+		app = update(app, keyPress('s'))
+		app = update(app, keyPress(tea.KeyEnter))
+		require.IsType(t, &ConfirmDialog{}, app.dialog)
+		snaps.MatchStandaloneSnapshot(t, app.View())
+
+		// cancel keeps the file
+		app = update(app, keyPress('n'))
+		assert.Nil(t, app.dialog)
+		content, _ := os.ReadFile(path)
+		assert.Equal(t, "package main\n", string(content))
+
+		app = update(app, keyPress('s'))
+		app = update(app, keyPress(tea.KeyEnter))
+		app = update(app, keyPress('y'))
+		assert.Nil(t, app.dialog)
+		content, _ = os.ReadFile(path)
+		assert.Equal(t, "package main\n\nfunc main() {}\n", string(content))
+
+		// saving the same content again doesn't ask
+		app = update(app, keyPress('s'))
+		app = update(app, keyPress(tea.KeyEnter))
+		assert.Nil(t, app.dialog)
+	})
+
+	t.Run("choosing a block", func(t *testing.T) {
+		t.Parallel()
+
+		app := bareApp(t)
+		app = update(app, streamFinished(replyWithCode))
+
+		app = update(app, keyPress('s'))
+		require.IsType(t, &CodeBlockPicker{}, app.dialog)
+
+		app = update(app, keyPress('j'))
+		app = update(app, keyPress(tea.KeyEnter))
+		require.IsType(t, &InputDialog{}, app.dialog)
+
+		app = typeText(app, "run.sh")
+		app = update(app, keyPress(tea.KeyEnter))
+
+		content, err := os.ReadFile(filepath.Join(app.cwd, "run.sh"))
+		require.NoError(t, err)
+		assert.Equal(t, "go run .\n", string(content))
+	})
+
+	t.Run("without a path", func(t *testing.T) {
+		t.Parallel()
+
+		app := bareApp(t)
+		app = update(app, streamFinished("```go\nx\n```\n"))
+
+		app = update(app, keyPress('s'))
+		app = update(app, keyPress(tea.KeyEnter))
+		assert.IsType(t, &ErrorDialog{}, app.dialog)
+	})
+}
@@ -0,0 +1,47 @@
+package app
+
+import "mark/internal/util"
+
+// scrollList is the cursor of a list showing a window of its entries,
+// scrolled to keep the cursor visible. The pickers render the entries from
+// offset to end.
+type scrollList struct {
+	rows   int // number of entries shown at once
+	count  int // number of entries
+	cursor int
+	offset int // index of the first visible entry
+}
+
+// setRows sets the number of entries shown at once.
+func (l *scrollList) setRows(rows int) {
+	l.rows = rows
+	l.moveCursor(0)
+}
+
+// reset replaces the entries by count new ones, the cursor on the first.
+func (l *scrollList) reset(count int) {
+	l.count = count
+	l.cursor = 0
+	l.offset = 0
+}
+
+func (l *scrollList) moveCursor(delta int) {
+	if l.count == 0 {
+		return
+	}
+
+	l.cursor = util.Clamp(l.cursor+delta, 0, l.count-1)
+
+	// keep the cursor visible
+	if l.cursor < l.offset {
+		l.offset = l.cursor
+	}
+	if l.cursor >= l.offset+l.rows {
+		l.offset = l.cursor - l.rows + 1
+	}
+}
+
+// end returns the index following the last visible entry.
+func (l *scrollList) end() int {
+	return min(l.offset+l.rows, l.count)
+}
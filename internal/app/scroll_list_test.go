@@ -0,0 +1,46 @@
+package app
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScrollList(t *testing.T) {
+	t.Parallel()
+
+	t.Run("keeps the cursor visible", func(t *testing.T) {
+		t.Parallel()
+
+		var list scrollList
+		list.setRows(3)
+		list.reset(10)
+
+		list.moveCursor(4)
+		assert.Equal(t, 4, list.cursor)
+		assert.Equal(t, 2, list.offset)
+		assert.Equal(t, 5, list.end())
+
+		list.moveCursor(-3)
+		assert.Equal(t, 1, list.offset)
+
+		list.moveCursor(20)
+		assert.Equal(t, 9, list.cursor)
+		assert.Equal(t, 10, list.end())
+
+		// fewer rows scroll to the cursor
+		list.moveCursor(-2)
+		list.setRows(1)
+		assert.Equal(t, 7, list.offset)
+	})
+
+	t.Run("empty", func(t *testing.T) {
+		t.Parallel()
+
+		var list scrollList
+		list.setRows(3)
+		list.moveCursor(1)
+		assert.Equal(t, 0, list.cursor)
+		assert.Equal(t, 0, list.end())
+	})
+}
@@ -0,0 +1,214 @@
+package app
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"strings"
+	"time"
+
+	"mark/internal/llm/provider"
+
+	"github.com/charmbracelet/bubbles/v2/spinner"
+	tea "github.com/charmbracelet/bubbletea/v2"
+	"github.com/charmbracelet/lipgloss/v2"
+	"github.com/charmbracelet/x/ansi"
+)
+
+// statusBarSeparator separates the parts of the status bar.
+const statusBarSeparator = " │ "
+
+var statusBarStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("8"))
+
+// StatusBar is the line at the bottom of the screen showing the model, the
+// progress of the current run, the token usage of the last response and
+// the control socket.
+type StatusBar struct {
+	width int
+
+	provider   string
+	model      string
+	persona    string // name of the persona of the session, empty for none
+	socketPath string
+
+	spinner    spinner.Model
+	running    bool
+	ran        bool      // a run was started, so there are stats to show
+	started    time.Time // start of the run
+	firstChunk time.Time // first chunk received, the start of the generation
+	finished   time.Time // end of the run
+	chunks     int
+	usage      provider.Usage
+	retry      provider.StreamEventRetry // last retry of the request, before the first chunk
+	retryAt    time.Time                 // when the request is sent again
+
+	now func() time.Time // replaced in tests
+}
+
+func NewStatusBar(providerName, model string) *StatusBar {
+	return &StatusBar{
+		provider: providerName,
+		model:    model,
+		spinner:  spinner.New(spinner.WithSpinner(spinner.MiniDot)),
+		now:      time.Now,
+	}
+}
+
+func (s *StatusBar) Focus() {}
+
+func (s *StatusBar) Blur() {}
+
+func (s *StatusBar) SetSize(width, height int) {
+	s.width = width
+}
+
+// SetSocketPath sets the control socket shown on the right.
+func (s *StatusBar) SetSocketPath(path string) {
+	s.socketPath = path
+}
+
+func (s *StatusBar) Update(app *App, msg tea.Msg) tea.Cmd {
+	switch msg := msg.(type) {
+	case spinner.TickMsg:
+		// stop ticking once the run is over
+		if !s.running {
+			return nil
+		}
+
+		var cmd tea.Cmd
+		s.spinner, cmd = s.spinner.Update(msg)
+		return cmd
+	}
+
+	return nil
+}
+
+func (s *StatusBar) View() string {
+	parts := []string{s.stateView(), s.provider + "/" + s.model}
+	if s.persona != "" {
+		parts = append(parts, "persona "+s.persona)
+	}
+	if rate := s.tokensPerSecond(); rate > 0 {
+		parts = append(parts, fmt.Sprintf("%.0f tok/s", rate))
+	}
+	if s.usage != (provider.Usage{}) {
+		parts = append(parts, fmt.Sprintf("in %d · out %d", s.usage.InputTokens, s.usage.OutputTokens))
+	}
+
+	left := strings.Join(parts, statusBarSeparator)
+	right := shortenHome(s.socketPath)
+
+	// the socket is right aligned when there's room for it
+	line := left
+	if right != "" {
+		gap := s.width - ansi.StringWidth(left) - ansi.StringWidth(right)
+		line = left + strings.Repeat(" ", max(gap, ansi.StringWidth(statusBarSeparator))) + right
+	}
+
+	return statusBarStyle.Render(ansi.Truncate(line, s.width, "…"))
+}
+
+// start resets the stats for a new run. Returns the command animating the
+// spinner.
+func (s *StatusBar) start() tea.Cmd {
+	s.running = true
+	s.ran = true
+	s.started = s.now()
+	s.firstChunk = time.Time{}
+	s.finished = time.Time{}
+	s.chunks = 0
+	s.usage = provider.Usage{}
+	s.retry = provider.StreamEventRetry{}
+	s.retryAt = time.Time{}
+
+	return s.spinner.Tick
+}
+
+// stop freezes the stats of the run.
+func (s *StatusBar) stop() {
+	if !s.running {
+		return
+	}
+
+	s.running = false
+	s.finished = s.now()
+}
+
+// chunksReceived counts the chunks of the reply, a frame carrying several.
+func (s *StatusBar) chunksReceived(n int) {
+	if s.chunks == 0 && n > 0 {
+		s.firstChunk = s.now()
+		s.retryAt = time.Time{}
+	}
+	s.chunks += n
+}
+
+// retrying shows the countdown to the retry of the request.
+func (s *StatusBar) retrying(retry provider.StreamEventRetry) {
+	s.retry = retry
+	s.retryAt = s.now().Add(retry.Delay)
+}
+
+func (s *StatusBar) setUsage(usage provider.Usage) {
+	s.usage = usage
+}
+
+// elapsed returns the duration of the run, up to now while it's running.
+func (s *StatusBar) elapsed() time.Duration {
+	if s.running {
+		return s.now().Sub(s.started)
+	}
+	return s.finished.Sub(s.started)
+}
+
+// tokensPerSecond returns the generation speed, from the first chunk on.
+// Chunks are counted as tokens until the usage is known.
+func (s *StatusBar) tokensPerSecond() float64 {
+	if s.chunks == 0 {
+		return 0
+	}
+
+	end := s.finished
+	if s.running {
+		end = s.now()
+	}
+	duration := end.Sub(s.firstChunk).Seconds()
+	if duration <= 0 {
+		return 0
+	}
+
+	tokens := s.chunks
+	if s.usage.OutputTokens > 0 {
+		tokens = s.usage.OutputTokens
+	}
+	return float64(tokens) / duration
+}
+
+func (s *StatusBar) stateView() string {
+	elapsed := s.elapsed().Round(100 * time.Millisecond).String()
+
+	switch {
+	case s.running && s.now().Before(s.retryAt):
+		wait := int(math.Ceil(s.retryAt.Sub(s.now()).Seconds()))
+		return fmt.Sprintf("%s retrying in %ds (%d/%d)", s.spinner.View(), wait, s.retry.Attempt, s.retry.MaxAttempts)
+	case s.running:
+		return s.spinner.View() + " " + elapsed
+	case s.ran:
+		return "done " + elapsed
+	default:
+		return "ready"
+	}
+}
+
+// shortenHome replaces the home directory at the start of path with "~".
+func shortenHome(path string) string {
+	home, err := os.UserHomeDir()
+	if err != nil || home == "" {
+		return path
+	}
+
+	if rest, ok := strings.CutPrefix(path, home); ok && (rest == "" || strings.HasPrefix(rest, string(os.PathSeparator))) {
+		return "~" + rest
+	}
+	return path
+}
@@ -0,0 +1,131 @@
+package app
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"mark/internal/llm/provider"
+
+	"github.com/charmbracelet/x/ansi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testTime = time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+
+// newTestStatusBar returns a status bar with a clock moved by the returned
+// function.
+func newTestStatusBar(width int) (*StatusBar, func(time.Duration)) {
+	now := testTime
+	status := NewStatusBar("openai", "gpt-4o")
+	status.now = func() time.Time { return now }
+	status.SetSize(width, 1)
+
+	return status, func(d time.Duration) { now = now.Add(d) }
+}
+
+func TestStatusBar(t *testing.T) {
+	t.Parallel()
+
+	t.Run("shows the model before any run", func(t *testing.T) {
+		t.Parallel()
+
+		status, _ := newTestStatusBar(60)
+		assert.Equal(t, "ready │ openai/gpt-4o", ansi.Strip(status.View()))
+	})
+
+	t.Run("shows the progress of the run", func(t *testing.T) {
+		t.Parallel()
+
+		status, advance := newTestStatusBar(60)
+		require.NotNil(t, status.start())
+
+		advance(500 * time.Millisecond)
+		status.chunksReceived(1)
+		advance(time.Second)
+		for range 4 {
+			status.chunksReceived(5) // several chunks per frame
+		}
+
+		assert.Equal(t, status.spinner.View()+" 1.5s │ openai/gpt-4o │ 21 tok/s", ansi.Strip(status.View()))
+	})
+
+	t.Run("shows the usage of the response", func(t *testing.T) {
+		t.Parallel()
+
+		status, advance := newTestStatusBar(60)
+		status.start()
+		status.chunksReceived(1)
+		advance(2 * time.Second)
+		status.setUsage(provider.Usage{InputTokens: 1200, OutputTokens: 80})
+		status.stop()
+
+		// the elapsed time doesn't change once the run is over
+		advance(time.Minute)
+
+		assert.Equal(t, "done 2s │ openai/gpt-4o │ 40 tok/s │ in 1200 · out 80", ansi.Strip(status.View()))
+	})
+
+	t.Run("counts down to the retry of the request", func(t *testing.T) {
+		t.Parallel()
+
+		status, advance := newTestStatusBar(60)
+		status.start()
+		status.retrying(provider.StreamEventRetry{Attempt: 2, MaxAttempts: 4, Delay: 3 * time.Second})
+		assert.Equal(t, status.spinner.View()+" retrying in 3s (2/4) │ openai/gpt-4o", ansi.Strip(status.View()))
+
+		advance(1500 * time.Millisecond)
+		assert.Equal(t, status.spinner.View()+" retrying in 2s (2/4) │ openai/gpt-4o", ansi.Strip(status.View()))
+
+		// the elapsed time is shown again once the request is sent
+		advance(2 * time.Second)
+		assert.Equal(t, status.spinner.View()+" 3.5s │ openai/gpt-4o", ansi.Strip(status.View()))
+	})
+
+	t.Run("stops the spinner after the run", func(t *testing.T) {
+		t.Parallel()
+
+		app := bareApp(t)
+		tick := app.status.start()
+		assert.NotNil(t, app.status.Update(&app, tick()))
+
+		app.status.stop()
+		assert.Nil(t, app.status.Update(&app, tick()))
+	})
+
+	t.Run("aligns the socket path to the right", func(t *testing.T) {
+		t.Parallel()
+
+		status, _ := newTestStatusBar(40)
+		status.SetSocketPath("/run/mark/a.sock")
+		assert.Equal(t, "ready │ openai/gpt-4o   /run/mark/a.sock", ansi.Strip(status.View()))
+
+		status.SetSize(30, 1)
+		assert.Equal(t, "ready │ openai/gpt-4o   /run/…", ansi.Strip(status.View()))
+	})
+
+	t.Run("shortens the home directory", func(t *testing.T) {
+		t.Parallel()
+
+		home, err := os.UserHomeDir()
+		require.NoError(t, err)
+
+		assert.Equal(t, "~"+string(filepath.Separator)+"a.sock", shortenHome(filepath.Join(home, "a.sock")))
+		assert.Equal(t, home+"x", shortenHome(home+"x"))
+	})
+
+	t.Run("shows the usage received by the app", func(t *testing.T) {
+		t.Parallel()
+
+		app := bareApp(t)
+		app.status.start()
+		app = update(app, streamStarted{})
+		app = update(app, chunkMsg("reply"))
+		app = update(app, streamUsageReceived{InputTokens: 10, OutputTokens: 3})
+		app = update(app, streamFinished("reply"))
+
+		assert.Contains(t, ansi.Strip(app.status.View()), "in 10 · out 3")
+	})
+}
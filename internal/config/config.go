@@ -0,0 +1,307 @@
+// Package config loads the configuration of mark. Settings are read in
+// layers, each overriding the previous ones: the defaults, the user config
+// file, the project config file, environment variables and flags.
+package config
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"mark/internal/persona"
+
+	"github.com/goccy/go-yaml"
+)
+
+// Config is the configuration of mark.
+type Config struct {
+	// Provider is the name of the LLM provider.
+	Provider string `yaml:"provider"`
+
+	// Model is the model used by the provider.
+	Model string `yaml:"model"`
+
+	// BaseURL replaces the URL of the API of the provider, to use a
+	// compatible server. Empty uses the URL of the provider.
+	BaseURL string `yaml:"base_url"`
+
+	// SystemPrompt is sent first in every request, unless a persona is
+	// selected. Empty sends no system prompt.
+	SystemPrompt string `yaml:"system_prompt"`
+
+	// Persona is the name of the persona selected in new sessions, see
+	// PersonaDirs. Empty uses SystemPrompt.
+	Persona string `yaml:"persona"`
+
+	// Theme is the style of rendered markdown, "auto" picks a dark or light
+	// style depending on the terminal.
+	Theme string `yaml:"theme"`
+
+	// SocketDir is the directory in which the private directory of the
+	// control sockets is created, it may be shared with other users. Empty
+	// uses the runtime directory.
+	SocketDir string `yaml:"socket_dir"`
+
+	// Keys overrides key bindings. It maps binding names, like
+	// "context.delete", to the keys triggering them. An empty list disables
+	// the binding.
+	Keys map[string][]string `yaml:"keys"`
+
+	// FrameRate is the number of times per second a streamed reply is
+	// updated. Zero uses the default.
+	FrameRate int `yaml:"frame_rate"`
+
+	// MaxRetries is the number of times a failed request is retried. Zero
+	// uses the default, a negative number disables retries.
+	MaxRetries int `yaml:"max_retries"`
+
+	// MaxRequestSize is the maximum size in bytes of a request sent to the
+	// control socket.
+	MaxRequestSize int `yaml:"max_request_size"`
+}
+
+// ProjectFile is the path of the project configuration file, relative to
+// the root of the project.
+var ProjectFile = filepath.Join(".mark", "config.yaml")
+
+// PersonaDirs returns the directories of the personas of the user and of
+// the project at projectDir, in the order they override each other. Empty
+// projectDir skips the project personas.
+func PersonaDirs(projectDir string) ([]string, error) {
+	dir, err := Dir()
+	if err != nil {
+		return nil, err
+	}
+
+	dirs := []string{filepath.Join(dir, "personas")}
+	if projectDir != "" {
+		dirs = append(dirs, filepath.Join(projectDir, ".mark", "personas"))
+	}
+	return dirs, nil
+}
+
+// Dir returns the directory of the user configuration:
+// $XDG_CONFIG_HOME/mark, falling back to ~/.config/mark.
+func Dir() (string, error) {
+	if dir := os.Getenv("XDG_CONFIG_HOME"); dir != "" {
+		return filepath.Join(dir, "mark"), nil
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to find config directory: %w", err)
+	}
+
+	return filepath.Join(home, ".config", "mark"), nil
+}
+
+// Path returns the path of the user configuration file.
+func Path() (string, error) {
+	dir, err := Dir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(dir, "config.yaml"), nil
+}
+
+// Options selects the sources of the configuration.
+type Options struct {
+	// UserFile is the user configuration file, which must exist. Empty uses
+	// Path, which may not exist.
+	UserFile string
+
+	// ProjectDir is the root of the project, containing ProjectFile. Empty
+	// skips the project configuration.
+	ProjectDir string
+
+	// Flags are the settings given on the command line, by setting name.
+	Flags map[string]string
+
+	// LookupEnv reads environment variables. Nil uses os.LookupEnv.
+	LookupEnv func(key string) (string, bool)
+
+	// Defaults are the settings used when no source sets them.
+	Defaults Config
+
+	// Validate checks the settings known to other packages, like the names
+	// of the providers or of the key bindings, returning an error for every
+	// invalid setting. Nil only checks what the config package knows.
+	Validate func(c Config, sources Sources) []error
+
+	// Settings names the settings to check, the others are read as they are.
+	// Commands talking to a running instance only check the settings they
+	// use. Empty checks every setting, with Validate.
+	Settings []string
+}
+
+// Load reads the configuration from the sources selected by options.
+// Invalid settings are reported together, along with where they were set.
+// The configuration and its sources are returned even when it's invalid,
+// so it can be shown.
+func Load(options Options) (Config, Sources, error) {
+	config := options.Defaults
+	config.Keys = maps.Clone(config.Keys) // the files add to the default bindings
+	sources := defaultSources()
+
+	userFile := options.UserFile
+	if userFile != "" {
+		// unlike the default file, a file given explicitly must exist
+		if _, err := os.Stat(userFile); err != nil {
+			return config, sources, fmt.Errorf("failed to read config: %w", err)
+		}
+	} else {
+		path, err := Path()
+		if err != nil {
+			return config, sources, err
+		}
+		userFile = path
+	}
+
+	files := []string{userFile}
+	if options.ProjectDir != "" {
+		files = append(files, filepath.Join(options.ProjectDir, ProjectFile))
+	}
+	for _, path := range files {
+		if err := mergeFile(&config, sources, path); err != nil {
+			return config, sources, err
+		}
+	}
+
+	lookupEnv := options.LookupEnv
+	if lookupEnv == nil {
+		lookupEnv = os.LookupEnv
+	}
+
+	checked := func(name string) bool {
+		return len(options.Settings) == 0 || slices.Contains(options.Settings, name)
+	}
+
+	var errs []error
+	for _, s := range settings {
+		if v, ok := lookupEnv(s.env()); ok {
+			if err := set(&config, sources, s, v, "env "+s.env()); checked(s.name) {
+				errs = append(errs, err)
+			}
+		}
+	}
+	for _, name := range slices.Sorted(maps.Keys(options.Flags)) {
+		s, ok := lookup(name)
+		if !ok {
+			errs = append(errs, fmt.Errorf("unknown setting %q", name))
+			continue
+		}
+		if err := set(&config, sources, s, options.Flags[name], "flag --"+s.flag()); checked(s.name) {
+			errs = append(errs, err)
+		}
+	}
+
+	for _, s := range settings {
+		if checked(s.name) {
+			errs = append(errs, config.validateSetting(s, sources))
+		}
+	}
+	if checked("persona") {
+		errs = append(errs, config.validatePersona(sources, options.ProjectDir))
+	}
+	if options.Validate != nil && len(options.Settings) == 0 {
+		errs = append(errs, options.Validate(config, sources)...)
+	}
+	if err := errors.Join(errs...); err != nil {
+		return config, sources, fmt.Errorf("invalid config: %w", err)
+	}
+
+	return config, sources, nil
+}
+
+// validatePersona checks that the selected persona exists.
+func (c Config) validatePersona(sources Sources, projectDir string) error {
+	if c.Persona == "" {
+		return nil
+	}
+
+	dirs, err := PersonaDirs(projectDir)
+	if err != nil {
+		return err
+	}
+	personas, err := persona.Load(dirs...)
+	if err != nil {
+		return err
+	}
+
+	if _, ok := persona.Find(personas, c.Persona); !ok {
+		return sources.Invalid("persona", fmt.Errorf("unknown persona %q, no %s%s in %s", c.Persona, c.Persona, persona.Ext, strings.Join(dirs, " or ")))
+	}
+	return nil
+}
+
+// set parses v into the setting s, recording source.
+func set(config *Config, sources Sources, s setting, v, source string) error {
+	if err := s.parse(config, v); err != nil {
+		return fmt.Errorf("%s: %w (%s)", s.name, err, source)
+	}
+	sources[s.name] = source
+	return nil
+}
+
+// readFile reads the configuration file at path, also returning the names
+// of the settings it sets. A missing file is an empty configuration.
+// Unknown settings are reported as errors, to catch typos.
+func readFile(path string) (Config, []string, error) {
+	config := Config{}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return config, nil, nil
+	}
+	if err != nil {
+		return config, nil, fmt.Errorf("failed to read config: %w", err)
+	}
+
+	err = yaml.UnmarshalWithOptions(data, &config, yaml.Strict())
+	if err != nil {
+		return config, nil, fmt.Errorf("invalid config %s: %w", path, err)
+	}
+
+	// tell settings set to their zero value from settings not set
+	present := map[string]any{}
+	if err := yaml.Unmarshal(data, &present); err != nil {
+		return config, nil, fmt.Errorf("invalid config %s: %w", path, err)
+	}
+
+	return config, slices.Sorted(maps.Keys(present)), nil
+}
+
+// mergeFile applies the settings of the configuration file at path. Key
+// bindings are merged one by one, so a project can override a binding
+// without dropping the user's other overrides.
+func mergeFile(config *Config, sources Sources, path string) error {
+	file, names, err := readFile(path)
+	if err != nil {
+		return err
+	}
+
+	for _, name := range names {
+		if name == "keys" {
+			if config.Keys == nil {
+				config.Keys = map[string][]string{}
+			}
+			for binding, keys := range file.Keys {
+				config.Keys[binding] = keys
+				sources["keys."+binding] = path
+			}
+			continue
+		}
+
+		if s, ok := lookup(name); ok {
+			s.copy(config, &file)
+			sources[name] = path
+		}
+	}
+
+	return nil
+}
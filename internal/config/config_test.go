@@ -0,0 +1,240 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeConfig(t *testing.T, content string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+func TestReadFile(t *testing.T) {
+	t.Parallel()
+
+	t.Run("when file does not exist", func(t *testing.T) {
+		t.Parallel()
+
+		config, _, err := readFile(filepath.Join(t.TempDir(), "config.yaml"))
+		require.NoError(t, err)
+		assert.Equal(t, Config{}, config)
+	})
+
+	t.Run("keys", func(t *testing.T) {
+		t.Parallel()
+
+		path := writeConfig(t, "keys:\n  context.delete: [x, delete]\n  main.new_session: []\n")
+
+		config, _, err := readFile(path)
+		require.NoError(t, err)
+		assert.Equal(t, map[string][]string{
+			"context.delete":   {"x", "delete"},
+			"main.new_session": {},
+		}, config.Keys)
+	})
+
+	t.Run("streaming", func(t *testing.T) {
+		t.Parallel()
+
+		config, _, err := readFile(writeConfig(t, "frame_rate: 60\nmax_retries: -1\n"))
+		require.NoError(t, err)
+		assert.Equal(t, 60, config.FrameRate)
+		assert.Equal(t, -1, config.MaxRetries)
+	})
+
+	t.Run("when a setting is unknown", func(t *testing.T) {
+		t.Parallel()
+
+		path := writeConfig(t, "kyes:\n  context.delete: [x]\n")
+
+		_, _, err := readFile(path)
+		assert.ErrorContains(t, err, "invalid config "+path)
+	})
+}
+
+func TestPath(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", "/config")
+
+	path, err := Path()
+	require.NoError(t, err)
+	assert.Equal(t, "/config/mark/config.yaml", path)
+}
+
+// writeProject writes the project config file of a new project and returns
+// the project directory.
+func writeProject(t *testing.T, content string) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	require.NoError(t, os.Mkdir(filepath.Join(dir, ".mark"), 0o700))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, ProjectFile), []byte(content), 0o600))
+	return dir
+}
+
+// env returns a LookupEnv reading vars.
+func env(vars map[string]string) func(string) (string, bool) {
+	return func(key string) (string, bool) {
+		v, ok := vars[key]
+		return v, ok
+	}
+}
+
+// testDefaults are the defaults of the tests, like those of the program.
+var testDefaults = Config{
+	Provider:       "openai",
+	Model:          "gpt-4o",
+	Theme:          "auto",
+	FrameRate:      30,
+	MaxRetries:     3,
+	MaxRequestSize: 64 * 1024 * 1024,
+}
+
+func TestLoad(t *testing.T) {
+	t.Parallel()
+
+	t.Run("defaults", func(t *testing.T) {
+		t.Parallel()
+
+		config, sources, err := Load(Options{UserFile: writeConfig(t, ""), LookupEnv: env(nil), Defaults: testDefaults})
+		require.NoError(t, err)
+		assert.Equal(t, testDefaults, config)
+		assert.Equal(t, defaultSources(), sources)
+	})
+
+	t.Run("layers", func(t *testing.T) {
+		t.Parallel()
+
+		user := writeConfig(t, "model: user-model\ntheme: dark\nframe_rate: 10\nmax_retries: 5\nkeys:\n  context.delete: [x]\n  main.zoom: [Z]\n")
+		project := writeProject(t, "model: project-model\nframe_rate: 20\nkeys:\n  context.delete: [X]\n")
+
+		config, sources, err := Load(Options{
+			UserFile:   user,
+			ProjectDir: project,
+			LookupEnv:  env(map[string]string{"MARK_FRAME_RATE": "30", "MARK_MAX_RETRIES": "6"}),
+			Flags:      map[string]string{"max_retries": "7"},
+			Defaults:   testDefaults,
+		})
+		require.NoError(t, err)
+
+		assert.Equal(t, "project-model", config.Model)
+		assert.Equal(t, "dark", config.Theme)
+		assert.Equal(t, 30, config.FrameRate)
+		assert.Equal(t, 7, config.MaxRetries)
+		assert.Equal(t, map[string][]string{"context.delete": {"X"}, "main.zoom": {"Z"}}, config.Keys)
+
+		projectFile := filepath.Join(project, ProjectFile)
+		assert.Equal(t, []Entry{
+			{"provider", "openai", SourceDefault},
+			{"model", "project-model", projectFile},
+			{"base_url", "", SourceDefault},
+			{"system_prompt", "", SourceDefault},
+			{"persona", "", SourceDefault},
+			{"theme", "dark", user},
+			{"socket_dir", "", SourceDefault},
+			{"frame_rate", "30", "env MARK_FRAME_RATE"},
+			{"max_retries", "7", "flag --max-retries"},
+			{"max_request_size", "67108864", SourceDefault},
+			{"keys.context.delete", "X", projectFile},
+			{"keys.main.zoom", "Z", user},
+		}, config.Entries(sources))
+	})
+
+	t.Run("settings set to their zero value", func(t *testing.T) {
+		t.Parallel()
+
+		config, sources, err := Load(Options{UserFile: writeConfig(t, "frame_rate: 0\n"), LookupEnv: env(nil), Defaults: testDefaults})
+		require.NoError(t, err)
+		assert.Equal(t, 0, config.FrameRate)
+		assert.NotEqual(t, SourceDefault, sources["frame_rate"])
+	})
+
+	t.Run("invalid settings name their source", func(t *testing.T) {
+		t.Parallel()
+
+		user := writeConfig(t, "provider: nope\nkeys:\n  main.nope: [x]\n")
+
+		_, sources, err := Load(Options{
+			UserFile:  user,
+			LookupEnv: env(map[string]string{"MARK_FRAME_RATE": "fast", "MARK_BASE_URL": "localhost:8080"}),
+			Flags:     map[string]string{"max_request_size": "0", "colour": "red"},
+			Defaults:  testDefaults,
+			Validate: func(c Config, sources Sources) []error {
+				return []error{
+					sources.Invalid("provider", fmt.Errorf("unknown provider %q", c.Provider)),
+					errors.New(`unknown key binding "main.nope"`),
+				}
+			},
+		})
+		require.Error(t, err)
+		require.NotNil(t, sources)
+
+		msg := err.Error()
+		assert.Contains(t, msg, `provider: unknown provider "nope" (`+user+")")
+		assert.Contains(t, msg, `frame_rate: "fast" is not a number (env MARK_FRAME_RATE)`)
+		assert.Contains(t, msg, `base_url: "localhost:8080" is not an http or https URL (env MARK_BASE_URL)`)
+		assert.Contains(t, msg, "max_request_size: must be positive (flag --max-request-size)")
+		assert.Contains(t, msg, `unknown setting "colour"`)
+		assert.Contains(t, msg, `unknown key binding "main.nope"`)
+	})
+
+	t.Run("only the selected settings are checked", func(t *testing.T) {
+		t.Parallel()
+
+		user := writeConfig(t, "persona: poet\nsocket_dir: /run/mark\n")
+		validate := func(c Config, sources Sources) []error {
+			return []error{errors.New(`unknown key binding "main.nope"`)}
+		}
+
+		config, _, err := Load(Options{
+			UserFile:  user,
+			LookupEnv: env(map[string]string{"MARK_FRAME_RATE": "fast"}),
+			Defaults:  testDefaults,
+			Validate:  validate,
+			Settings:  []string{"socket_dir"},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, "/run/mark", config.SocketDir)
+
+		_, _, err = Load(Options{
+			UserFile:  user,
+			LookupEnv: env(map[string]string{"MARK_SOCKET_DIR": "run/mark"}),
+			Defaults:  testDefaults,
+			Validate:  validate,
+			Settings:  []string{"socket_dir"},
+		})
+		assert.EqualError(t, err, `invalid config: socket_dir: "run/mark" is not an absolute path (env MARK_SOCKET_DIR)`)
+	})
+
+	t.Run("persona", func(t *testing.T) {
+		t.Parallel()
+
+		project := writeProject(t, "persona: reviewer\n")
+		require.NoError(t, os.Mkdir(filepath.Join(project, ".mark", "personas"), 0o700))
+		require.NoError(t, os.WriteFile(filepath.Join(project, ".mark", "personas", "reviewer.md"), []byte("Review the code."), 0o600))
+
+		config, _, err := Load(Options{UserFile: writeConfig(t, ""), ProjectDir: project, LookupEnv: env(nil), Defaults: testDefaults})
+		require.NoError(t, err)
+		assert.Equal(t, "reviewer", config.Persona)
+
+		_, _, err = Load(Options{UserFile: writeConfig(t, ""), ProjectDir: project, LookupEnv: env(nil), Flags: map[string]string{"persona": "poet"}, Defaults: testDefaults})
+		assert.ErrorContains(t, err, `persona: unknown persona "poet", no poet.md in `)
+		assert.ErrorContains(t, err, "(flag --persona)")
+	})
+
+	t.Run("a user file given explicitly must exist", func(t *testing.T) {
+		t.Parallel()
+
+		_, _, err := Load(Options{UserFile: filepath.Join(t.TempDir(), "missing.yaml"), LookupEnv: env(nil), Defaults: testDefaults})
+		assert.ErrorContains(t, err, "failed to read config")
+	})
+}
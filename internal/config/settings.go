@@ -0,0 +1,258 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"net/url"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// maxFrameRate bounds the frame rate, faster updates can't be seen anyway.
+const maxFrameRate = 240
+
+// SourceDefault is the source of the settings left to their default.
+const SourceDefault = "default"
+
+// Sources maps setting names to where they were set: SourceDefault, the
+// path of a config file, "env MARK_MODEL" or "flag --model". Key bindings
+// are named "keys." followed by the binding name.
+type Sources map[string]string
+
+// Invalid returns err as the error of the setting name, naming where it was
+// set.
+func (s Sources) Invalid(name string, err error) error {
+	return fmt.Errorf("%s: %w (%s)", name, err, s[name])
+}
+
+// setting is a setting of the Config that can be set by every source.
+type setting struct {
+	name string // name in config files
+	desc string
+	// field returns a pointer to the setting in c, a *string or an *int.
+	field    func(c *Config) any
+	validate func(c Config) error
+}
+
+// env returns the environment variable setting s, like MARK_MAX_RETRIES.
+func (s setting) env() string {
+	return "MARK_" + strings.ToUpper(s.name)
+}
+
+// flag returns the flag setting s, like max-retries.
+func (s setting) flag() string {
+	return strings.ReplaceAll(s.name, "_", "-")
+}
+
+// parse sets s in c from the value of an environment variable or a flag.
+func (s setting) parse(c *Config, v string) error {
+	switch field := s.field(c).(type) {
+	case *string:
+		*field = v
+	case *int:
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return fmt.Errorf("%q is not a number", v)
+		}
+		*field = n
+	}
+	return nil
+}
+
+// copy sets s in dst to its value in src.
+func (s setting) copy(dst, src *Config) {
+	switch field := s.field(dst).(type) {
+	case *string:
+		*field = *s.field(src).(*string)
+	case *int:
+		*field = *s.field(src).(*int)
+	}
+}
+
+// format returns the value of s in c.
+func (s setting) format(c *Config) string {
+	switch field := s.field(c).(type) {
+	case *string:
+		return *field
+	case *int:
+		return strconv.Itoa(*field)
+	}
+	return ""
+}
+
+// settings lists the settings in the order they're shown. Key bindings are
+// only set in config files, so they're handled apart.
+var settings = []setting{
+	{
+		name:  "provider",
+		desc:  "LLM provider",
+		field: func(c *Config) any { return &c.Provider },
+		validate: func(c Config) error {
+			if c.Provider == "" {
+				return errors.New("must not be empty")
+			}
+			return nil
+		},
+	},
+	{
+		name:  "model",
+		desc:  "model used by the provider",
+		field: func(c *Config) any { return &c.Model },
+		validate: func(c Config) error {
+			if c.Model == "" {
+				return errors.New("must not be empty")
+			}
+			return nil
+		},
+	},
+	{
+		name:  "base_url",
+		desc:  "URL of a server compatible with the API of the provider",
+		field: func(c *Config) any { return &c.BaseURL },
+		validate: func(c Config) error {
+			if c.BaseURL == "" {
+				return nil
+			}
+			u, err := url.Parse(c.BaseURL)
+			if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				return fmt.Errorf("%q is not an http or https URL", c.BaseURL)
+			}
+			return nil
+		},
+	},
+	{
+		name:     "system_prompt",
+		desc:     "system prompt sent when no persona is selected",
+		field:    func(c *Config) any { return &c.SystemPrompt },
+		validate: func(c Config) error { return nil },
+	},
+	{
+		name:     "persona",
+		desc:     "persona selected in new sessions, a file of the personas directories without its extension",
+		field:    func(c *Config) any { return &c.Persona },
+		validate: func(c Config) error { return nil },
+	},
+	{
+		name:     "theme",
+		desc:     "style of rendered markdown, auto picks a dark or light style",
+		field:    func(c *Config) any { return &c.Theme },
+		validate: func(c Config) error { return nil },
+	},
+	{
+		name:  "socket_dir",
+		desc:  "directory in which a private directory of the control sockets is created",
+		field: func(c *Config) any { return &c.SocketDir },
+		validate: func(c Config) error {
+			if c.SocketDir != "" && !filepath.IsAbs(c.SocketDir) {
+				return fmt.Errorf("%q is not an absolute path", c.SocketDir)
+			}
+			return nil
+		},
+	},
+	{
+		name:  "frame_rate",
+		desc:  "updates per second of a streamed reply",
+		field: func(c *Config) any { return &c.FrameRate },
+		validate: func(c Config) error {
+			if c.FrameRate < 0 || c.FrameRate > maxFrameRate {
+				return fmt.Errorf("must be between 0 and %d", maxFrameRate)
+			}
+			return nil
+		},
+	},
+	{
+		name:     "max_retries",
+		desc:     "retries of a failed request, a negative number disables them",
+		field:    func(c *Config) any { return &c.MaxRetries },
+		validate: func(c Config) error { return nil },
+	},
+	{
+		name:  "max_request_size",
+		desc:  "maximum size in bytes of a request sent to the control socket",
+		field: func(c *Config) any { return &c.MaxRequestSize },
+		validate: func(c Config) error {
+			if c.MaxRequestSize <= 0 {
+				return errors.New("must be positive")
+			}
+			return nil
+		},
+	},
+}
+
+// lookup returns the setting with the name.
+func lookup(name string) (setting, bool) {
+	i := slices.IndexFunc(settings, func(s setting) bool { return s.name == name })
+	if i < 0 {
+		return setting{}, false
+	}
+	return settings[i], true
+}
+
+func defaultSources() Sources {
+	sources := Sources{}
+	for _, s := range settings {
+		sources[s.name] = SourceDefault
+	}
+	return sources
+}
+
+// validateSetting returns an error if the setting s is invalid, naming where
+// it was set. Key bindings are checked by the App.
+func (c Config) validateSetting(s setting, sources Sources) error {
+	if err := s.validate(c); err != nil {
+		return sources.Invalid(s.name, err)
+	}
+	return nil
+}
+
+// Flag is a command line flag setting a setting.
+type Flag struct {
+	Name    string // name of the flag, like max-retries
+	Setting string // name of the setting, like max_retries
+	Usage   string
+	Int     bool // the value is a number
+}
+
+// Flags returns the flags setting the settings.
+func Flags() []Flag {
+	var flags []Flag
+	for _, s := range settings {
+		_, isInt := s.field(&Config{}).(*int)
+		flags = append(flags, Flag{
+			Name:    s.flag(),
+			Setting: s.name,
+			Usage:   s.desc + " (env " + s.env() + ")",
+			Int:     isInt,
+		})
+	}
+	return flags
+}
+
+// Entry is a setting of the effective configuration.
+type Entry struct {
+	Name   string
+	Value  string
+	Source string
+}
+
+// Entries lists the settings of c with their sources, key bindings last.
+func (c Config) Entries(sources Sources) []Entry {
+	var entries []Entry
+	for _, s := range settings {
+		entries = append(entries, Entry{Name: s.name, Value: s.format(&c), Source: sources[s.name]})
+	}
+
+	for _, binding := range slices.Sorted(maps.Keys(c.Keys)) {
+		keys := c.Keys[binding]
+		value := strings.Join(keys, ", ")
+		if len(keys) == 0 {
+			value = "(disabled)"
+		}
+		entries = append(entries, Entry{Name: "keys." + binding, Value: value, Source: sources["keys."+binding]})
+	}
+
+	return entries
+}
@@ -25,6 +25,19 @@ func (c *Context) DeleteItem(index int) {
 	c.items = slices.Delete(c.items, index, index+1)
 }
 
+// Index returns the index of item, or -1 if it isn't in the context.
+func (c *Context) Index(item ContextItem) int {
+	return slices.Index(c.items, item)
+}
+
+// ReplaceItem replaces the item at index, keeping its position.
+func (c *Context) ReplaceItem(index int, item ContextItem) {
+	if index < 0 || index >= len(c.items) {
+		return
+	}
+	c.items[index] = item
+}
+
 func (c *Context) Message() string {
 	var message string
 
@@ -2,6 +2,7 @@ package domain
 
 import (
 	"fmt"
+	"io/fs"
 	"os"
 	"path/filepath"
 
@@ -23,6 +24,10 @@ func (item ContextItemFile) Title() string {
 	return "File: " + item.path
 }
 
+func (item ContextItemFile) Path() string {
+	return item.path
+}
+
 func (item ContextItemFile) Message() string {
 	var result string
 	result += "File: " + item.path + "\n"
@@ -57,6 +62,17 @@ func FileItem(path string) (ContextItem, error) {
 		}
 	}
 
+	info, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("%s: %w", path, fs.ErrNotExist)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to check file: %w", err)
+	}
+	if info.IsDir() {
+		return nil, fmt.Errorf("%s is a directory", path)
+	}
+
 	return ContextItemFile{
 		path: path,
 	}, nil
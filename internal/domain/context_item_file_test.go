@@ -1,6 +1,7 @@
 package domain
 
 import (
+	"io/fs"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -41,8 +42,8 @@ func TestContextItemFile(t *testing.T) {
 		t.Run("when file does not exist", func(t *testing.T) {
 			t.Parallel()
 
-			item, err := FileItem("testdata/nonexistent.txt")
-			require.NoError(t, err)
+			// the file was removed after being added
+			item := ContextItemFile{path: "testdata/nonexistent.txt"}
 
 			actual := item.Message()
 
@@ -51,4 +52,23 @@ func TestContextItemFile(t *testing.T) {
 			assert.Equal(t, expected, actual)
 		})
 	})
+
+	t.Run("FileItem", func(t *testing.T) {
+		t.Parallel()
+
+		t.Run("when file does not exist", func(t *testing.T) {
+			t.Parallel()
+
+			_, err := FileItem("testdata/nonexistent.txt")
+			assert.EqualError(t, err, "testdata/nonexistent.txt: file does not exist")
+			assert.ErrorIs(t, err, fs.ErrNotExist)
+		})
+
+		t.Run("when path is a directory", func(t *testing.T) {
+			t.Parallel()
+
+			_, err := FileItem("testdata")
+			assert.EqualError(t, err, "testdata is a directory")
+		})
+	})
 }
@@ -27,6 +27,32 @@ func TestContext(t *testing.T) {
 
 		c.DeleteItem(0) // Deleting from an empty context should not panic
 	})
+
+	t.Run("ReplaceItem", func(t *testing.T) {
+		c := NewContext()
+
+		c.AddItem(mockItem("item 1"))
+		c.AddItem(mockItem("item 2"))
+
+		c.ReplaceItem(0, mockItem("item 3"))
+		assert.Equal(t, []ContextItem{mockItem("item 3"), mockItem("item 2")}, c.Items())
+
+		c.ReplaceItem(2, mockItem("item 4")) // Replacing out of range should not panic
+		assert.Equal(t, 2, len(c.Items()))
+	})
+
+	t.Run("Index", func(t *testing.T) {
+		c := NewContext()
+
+		first, second := TextItem("item 1"), TextItem("item 2")
+		c.AddItem(first)
+		c.AddItem(second)
+		assert.Equal(t, 1, c.Index(second))
+
+		c.DeleteItem(0)
+		assert.Equal(t, 0, c.Index(second))
+		assert.Equal(t, -1, c.Index(first))
+	})
 }
 
 func mockItem(text string) ContextItem {
@@ -1,7 +1,11 @@
 package domain
 
+import "mark/internal/persona"
+
 type Session struct {
 	context *Context
+	persona persona.Persona
+	prompt  string
 	reply   string
 }
 
@@ -27,6 +31,27 @@ func (session *Session) Reply() string {
 	return session.reply
 }
 
+// SetPrompt sets the question asked to the agent, which is sent separately
+// from the context.
+func (session *Session) SetPrompt(prompt string) {
+	session.prompt = prompt
+}
+
+func (session *Session) Prompt() string {
+	return session.prompt
+}
+
+// SetPersona sets the persona whose system prompt is sent first.
+func (session *Session) SetPersona(p persona.Persona) {
+	session.persona = p
+}
+
+// Persona returns the persona of the session. Its name is empty when no
+// persona is selected, the prompt being the configured system prompt.
+func (session *Session) Persona() persona.Persona {
+	return session.persona
+}
+
 func (session *Session) Context() *Context {
 	return session.context
 }
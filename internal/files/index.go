@@ -0,0 +1,96 @@
+// Package files indexes the files of a project.
+package files
+
+import (
+	"io/fs"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"mark/internal/util"
+)
+
+// Index returns the paths of the files under dir, relative to dir and using
+// forward slashes. Inside a git repository, files ignored by git are
+// skipped. Otherwise all files are returned, except for the ones inside
+// hidden directories.
+func Index(dir string) ([]string, error) {
+	files, err := gitFiles(dir)
+	if err != nil {
+		files, err = walkFiles(dir)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	slices.Sort(files)
+
+	return files, nil
+}
+
+// Directories returns the directories containing the given files, including
+// intermediate ones, sorted and with a trailing slash.
+func Directories(files []string) []string {
+	seen := map[string]bool{}
+	var dirs []string
+
+	for _, file := range files {
+		for dir := path.Dir(file); dir != "."; dir = path.Dir(dir) {
+			if seen[dir] {
+				break
+			}
+			seen[dir] = true
+			dirs = append(dirs, dir+"/")
+		}
+	}
+
+	slices.Sort(dirs)
+
+	return dirs
+}
+
+// gitFiles lists tracked and untracked files, respecting .gitignore.
+func gitFiles(dir string) ([]string, error) {
+	output, err := util.RunShellCommand("git", "-C", dir, "ls-files", "--cached", "--others", "--exclude-standard")
+	if err != nil {
+		return nil, err
+	}
+
+	var files []string
+	for _, line := range strings.Split(output, "\n") {
+		if line != "" {
+			files = append(files, line)
+		}
+	}
+
+	return files, nil
+}
+
+// walkFiles lists files walking the file system.
+func walkFiles(dir string) ([]string, error) {
+	var files []string
+
+	err := filepath.WalkDir(dir, func(p string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return nil // skip unreadable entries
+		}
+
+		if entry.IsDir() {
+			if p != dir && strings.HasPrefix(entry.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		files = append(files, filepath.ToSlash(rel))
+
+		return nil
+	})
+
+	return files, err
+}
@@ -0,0 +1,58 @@
+package files
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeFiles(t *testing.T, dir string, paths ...string) {
+	for _, p := range paths {
+		p = filepath.Join(dir, p)
+		require.NoError(t, os.MkdirAll(filepath.Dir(p), 0o755))
+		require.NoError(t, os.WriteFile(p, []byte("content"), 0o644))
+	}
+}
+
+func TestIndex(t *testing.T) {
+	t.Parallel()
+
+	t.Run("git repository", func(t *testing.T) {
+		t.Parallel()
+
+		if _, err := exec.LookPath("git"); err != nil {
+			t.Skip("git is not installed")
+		}
+
+		dir := t.TempDir()
+		require.NoError(t, exec.Command("git", "-C", dir, "init", "-q").Run())
+		writeFiles(t, dir, "a.txt", "sub/b.txt", "ignored.txt", "build/out.bin")
+		require.NoError(t, os.WriteFile(filepath.Join(dir, ".gitignore"), []byte("ignored.txt\nbuild/\n"), 0o644))
+
+		files, err := Index(dir)
+		require.NoError(t, err)
+		assert.Equal(t, []string{".gitignore", "a.txt", "sub/b.txt"}, files)
+	})
+
+	t.Run("plain directory", func(t *testing.T) {
+		t.Parallel()
+
+		dir := t.TempDir()
+		writeFiles(t, dir, "b.txt", "sub/deeper/c.txt", ".hidden/d.txt", ".env")
+
+		files, err := Index(dir)
+		require.NoError(t, err)
+		assert.Equal(t, []string{".env", "b.txt", "sub/deeper/c.txt"}, files)
+	})
+}
+
+func TestDirectories(t *testing.T) {
+	t.Parallel()
+
+	dirs := Directories([]string{"a.txt", "sub/b.txt", "sub/deeper/c.txt", "other/d.txt"})
+	assert.Equal(t, []string{"other/", "sub/", "sub/deeper/"}, dirs)
+}
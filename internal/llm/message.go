@@ -5,6 +5,7 @@ type Role int
 const (
 	RoleUser Role = iota
 	RoleAssistant
+	RoleSystem
 )
 
 type Message struct {
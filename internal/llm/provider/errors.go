@@ -0,0 +1,103 @@
+package provider
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Classes of API errors, matched with errors.Is.
+var (
+	ErrRateLimited    = errors.New("rate limited")
+	ErrUnavailable    = errors.New("service unavailable")
+	ErrUnauthorized   = errors.New("unauthorized")
+	ErrMissingAPIKey  = errors.New("missing API key")
+	ErrContextTooLong = errors.New("context too long")
+)
+
+// APIError is an error response from the API of a provider.
+type APIError struct {
+	StatusCode int
+	// RetryAfter is how long the API asked to wait before retrying, zero
+	// when it didn't say.
+	RetryAfter time.Duration
+	// Class is set by providers knowing more than the status code tells,
+	// like ErrContextTooLong.
+	Class error
+	Err   error
+}
+
+func (e *APIError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the error of the response and its classes.
+func (e *APIError) Unwrap() []error {
+	errs := []error{e.Err}
+	if e.Class != nil {
+		errs = append(errs, e.Class)
+	}
+
+	switch {
+	case e.StatusCode == http.StatusTooManyRequests:
+		errs = append(errs, ErrRateLimited)
+	case e.StatusCode == http.StatusUnauthorized || e.StatusCode == http.StatusForbidden:
+		errs = append(errs, ErrUnauthorized)
+	case e.StatusCode >= 500:
+		errs = append(errs, ErrUnavailable)
+	}
+
+	return errs
+}
+
+// RetryError is the error of a request that still failed after retries.
+type RetryError struct {
+	Attempts int
+	Err      error // error of the last attempt
+}
+
+func (e *RetryError) Error() string {
+	return fmt.Sprintf("gave up after %d attempts: %v", e.Attempts, e.Err)
+}
+
+func (e *RetryError) Unwrap() error {
+	return e.Err
+}
+
+// Retryable checks if a request failing with err can be sent again: the API
+// is rate limited, overloaded or failing, or the network failed.
+func Retryable(err error) bool {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		switch apiErr.StatusCode {
+		case http.StatusRequestTimeout, http.StatusConflict, http.StatusTooManyRequests:
+			return true
+		}
+		return apiErr.StatusCode >= 500
+	}
+
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
+
+// ParseRetryAfter returns how long the headers of a response ask to wait
+// before retrying, from retry-after-ms or Retry-After in seconds or as a
+// date. Returns zero when there's no valid header.
+func ParseRetryAfter(header http.Header) time.Duration {
+	if ms, err := strconv.ParseFloat(header.Get("Retry-After-Ms"), 64); err == nil && ms > 0 {
+		return time.Duration(ms * float64(time.Millisecond))
+	}
+
+	value := header.Get("Retry-After")
+	if seconds, err := strconv.ParseFloat(value, 64); err == nil && seconds > 0 {
+		return time.Duration(seconds * float64(time.Second))
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		return max(time.Until(date), 0)
+	}
+
+	return 0
+}
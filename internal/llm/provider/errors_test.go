@@ -0,0 +1,45 @@
+package provider_test
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"mark/internal/llm/provider"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRetryable(t *testing.T) {
+	t.Parallel()
+
+	assert.True(t, provider.Retryable(apiError(http.StatusTooManyRequests, 0)))
+	assert.True(t, provider.Retryable(apiError(http.StatusServiceUnavailable, 0)))
+	assert.True(t, provider.Retryable(&net.OpError{Op: "dial", Err: errors.New("connection refused")}))
+
+	assert.False(t, provider.Retryable(apiError(http.StatusBadRequest, 0)))
+	assert.False(t, provider.Retryable(apiError(http.StatusUnauthorized, 0)))
+	assert.False(t, provider.Retryable(provider.ErrEmptyResponse))
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	t.Parallel()
+
+	header := func(pairs ...string) http.Header {
+		h := http.Header{}
+		for i := 0; i < len(pairs); i += 2 {
+			h.Set(pairs[i], pairs[i+1])
+		}
+		return h
+	}
+
+	assert.Equal(t, 2*time.Second, provider.ParseRetryAfter(header("Retry-After", "2")))
+	assert.Equal(t, 1500*time.Millisecond, provider.ParseRetryAfter(header("Retry-After-Ms", "1500", "Retry-After", "2")))
+	assert.Zero(t, provider.ParseRetryAfter(header()))
+	assert.Zero(t, provider.ParseRetryAfter(header("Retry-After", "soon")))
+
+	date := time.Now().Add(time.Minute).UTC().Format(http.TimeFormat)
+	assert.InDelta(t, time.Minute, provider.ParseRetryAfter(header("Retry-After", date)), float64(2*time.Second))
+}
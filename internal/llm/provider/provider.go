@@ -2,10 +2,14 @@ package provider
 
 import (
 	"context"
+	"errors"
 
 	"mark/internal/llm"
 )
 
+// ErrEmptyResponse is reported when a completion has no content.
+var ErrEmptyResponse = errors.New("empty response")
+
 type StreamingEvent any
 
 type StreamEventChunk struct {
@@ -18,8 +22,50 @@ type StreamEventError struct {
 
 type StreamEventEnd struct {
 	Message string
+	Usage   Usage
+}
+
+// Usage is the number of tokens used by a completion, as reported by the
+// provider. It's zero when the provider doesn't report it.
+type Usage struct {
+	InputTokens  int
+	OutputTokens int
 }
 
+// Provider completes conversations with a model.
+//
+// The channel returned by CompleteStreaming follows this contract, checked
+// by the providertest package:
+//   - chunks are sent as they're received, followed by exactly one
+//     StreamEventEnd or StreamEventError, then the channel is closed
+//   - a StreamEventRetry can be sent before the first chunk, when a failed
+//     request is retried
+//   - StreamEventEnd has the whole message, which is never empty: a response
+//     without content is a StreamEventError with ErrEmptyResponse
+//   - when ctx is cancelled, the stream stops without a StreamEventEnd or
+//     StreamEventError and the channel is closed, even if nobody receives
+//     the events
 type Provider interface {
+	// Name identifies the provider, like "openai".
+	Name() string
+	// Model is the model used for completions.
+	Model() string
+
 	CompleteStreaming(ctx context.Context, messages []llm.Message) (<-chan StreamingEvent, error)
 }
+
+// Send sends event to events unless ctx is done first. Returns false if the
+// event wasn't sent, in which case the stream should stop.
+func Send(ctx context.Context, events chan<- StreamingEvent, event StreamingEvent) bool {
+	// a cancelled context wins over a receiver ready to receive
+	if ctx.Err() != nil {
+		return false
+	}
+
+	select {
+	case events <- event:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
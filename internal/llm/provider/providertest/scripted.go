@@ -0,0 +1,120 @@
+// Package providertest checks that providers follow the contract of
+// provider.Provider, and provides a scripted provider for tests.
+package providertest
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"mark/internal/llm"
+	"mark/internal/llm/provider"
+)
+
+// Script is the response of the backend of a provider.
+type Script struct {
+	// Chunks is the content streamed, in order.
+	Chunks []string
+	// Delay is waited before each chunk.
+	Delay time.Duration
+	// Usage is reported at the end of the response.
+	Usage provider.Usage
+	// Err, when not nil, fails the response after the chunks.
+	Err error
+	// Hang stops the response after the chunks, without ending it, until
+	// the request is cancelled.
+	Hang bool
+}
+
+// Reply returns the content of the response.
+func (s Script) Reply() string {
+	return strings.Join(s.Chunks, "")
+}
+
+// Words returns a script streaming the words of text, keeping the spaces.
+func Words(text string) Script {
+	var chunks []string
+	for _, word := range strings.SplitAfter(text, " ") {
+		if word != "" {
+			chunks = append(chunks, word)
+		}
+	}
+	return Script{Chunks: chunks}
+}
+
+// Scripted is a provider replying to requests with scripts, in order. The
+// last script replies to the remaining requests.
+type Scripted struct {
+	mu       sync.Mutex
+	scripts  []Script
+	requests int
+}
+
+func NewScripted(scripts ...Script) *Scripted {
+	return &Scripted{scripts: scripts}
+}
+
+// Requests returns the number of requests received.
+func (p *Scripted) Requests() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.requests
+}
+
+// next returns the script of the next request.
+func (p *Scripted) next() Script {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.requests++
+	if len(p.scripts) == 0 {
+		return Script{}
+	}
+	return p.scripts[min(p.requests, len(p.scripts))-1]
+}
+
+func (p *Scripted) Name() string {
+	return "scripted"
+}
+
+func (p *Scripted) Model() string {
+	return "script"
+}
+
+func (p *Scripted) CompleteStreaming(ctx context.Context, messages []llm.Message) (<-chan provider.StreamingEvent, error) {
+	events := make(chan provider.StreamingEvent)
+	script := p.next()
+
+	go func() {
+		defer close(events)
+
+		for _, chunk := range script.Chunks {
+			if script.Delay > 0 {
+				select {
+				case <-time.After(script.Delay):
+				case <-ctx.Done():
+					return
+				}
+			}
+
+			if !provider.Send(ctx, events, provider.StreamEventChunk{Chunk: chunk}) {
+				return
+			}
+		}
+
+		switch {
+		case script.Hang:
+			<-ctx.Done()
+		case script.Err != nil:
+			provider.Send(ctx, events, provider.StreamEventError{Error: script.Err})
+		case script.Reply() == "":
+			provider.Send(ctx, events, provider.StreamEventError{Error: provider.ErrEmptyResponse})
+		default:
+			provider.Send(ctx, events, provider.StreamEventEnd{Message: script.Reply(), Usage: script.Usage})
+		}
+	}()
+
+	return events, nil
+}
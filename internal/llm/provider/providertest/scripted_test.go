@@ -0,0 +1,15 @@
+package providertest
+
+import (
+	"testing"
+
+	"mark/internal/llm/provider"
+)
+
+func TestScripted(t *testing.T) {
+	t.Parallel()
+
+	Run(t, func(t *testing.T, script Script) provider.Provider {
+		return NewScripted(script)
+	})
+}
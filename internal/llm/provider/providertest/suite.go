@@ -0,0 +1,172 @@
+package providertest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"mark/internal/llm"
+	"mark/internal/llm/provider"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// closeTimeout is how long a provider has to close the channel.
+const closeTimeout = 5 * time.Second
+
+// Factory returns a provider whose backend responds with script.
+type Factory func(t *testing.T, script Script) provider.Provider
+
+var messages = []llm.Message{{Role: llm.RoleUser, Content: "Say hello"}}
+
+// Run checks that the providers returned by factory follow the contract of
+// provider.Provider.
+func Run(t *testing.T, factory Factory) {
+	t.Run("streams the reply", func(t *testing.T) {
+		t.Parallel()
+
+		script := Script{
+			Chunks: []string{"Hello", ", ", "world", "!"},
+			Usage:  provider.Usage{InputTokens: 3, OutputTokens: 4},
+		}
+		events := collect(t, start(t, factory(t, script), context.Background()))
+
+		var chunks strings.Builder
+		for _, event := range events[:len(events)-1] {
+			chunk, ok := event.(provider.StreamEventChunk)
+			require.True(t, ok, "unexpected event before the end: %#v", event)
+			chunks.WriteString(chunk.Chunk)
+		}
+		assert.Equal(t, script.Reply(), chunks.String())
+
+		assert.Equal(t, provider.StreamEventEnd{Message: script.Reply(), Usage: script.Usage}, events[len(events)-1])
+	})
+
+	t.Run("reports an empty response as an error", func(t *testing.T) {
+		t.Parallel()
+
+		events := collect(t, start(t, factory(t, Script{}), context.Background()))
+
+		require.Len(t, events, 1)
+		requireError(t, events[0], provider.ErrEmptyResponse)
+	})
+
+	t.Run("reports a failure as an error", func(t *testing.T) {
+		t.Parallel()
+
+		events := collect(t, start(t, factory(t, Script{Err: errors.New("backend failure")}), context.Background()))
+
+		require.Len(t, events, 1)
+		requireError(t, events[0], nil)
+	})
+
+	t.Run("reports a failure after chunks as an error", func(t *testing.T) {
+		t.Parallel()
+
+		script := Script{Chunks: []string{"Hello"}, Err: errors.New("backend failure")}
+		events := collect(t, start(t, factory(t, script), context.Background()))
+
+		require.Len(t, events, 2)
+		assert.Equal(t, provider.StreamEventChunk{Chunk: "Hello"}, events[0])
+		requireError(t, events[1], nil)
+	})
+
+	t.Run("stops when cancelled", func(t *testing.T) {
+		t.Parallel()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		events := start(t, factory(t, Script{Chunks: []string{"Hello", "world"}, Hang: true}), ctx)
+
+		first := <-events
+		assert.Equal(t, provider.StreamEventChunk{Chunk: "Hello"}, first)
+		cancel()
+
+		for _, event := range collect(t, events) {
+			assert.IsType(t, provider.StreamEventChunk{}, event, "unexpected event after the cancellation")
+		}
+	})
+
+	t.Run("doesn't wait for the events to be received when cancelled", func(t *testing.T) {
+		t.Parallel()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		script := Script{}
+		for i := range 1000 {
+			script.Chunks = append(script.Chunks, fmt.Sprintf("chunk %d ", i))
+		}
+
+		events := start(t, factory(t, script), ctx)
+		<-events
+		cancel()
+		time.Sleep(10 * time.Millisecond) // the provider stops without anyone receiving
+
+		received := collect(t, events)
+		assert.Less(t, len(received), len(script.Chunks)/2)
+		for _, event := range received {
+			assert.IsType(t, provider.StreamEventChunk{}, event, "unexpected event after the cancellation")
+		}
+	})
+
+	t.Run("closes the channel when cancelled before starting", func(t *testing.T) {
+		t.Parallel()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		events := collect(t, start(t, factory(t, Script{Chunks: []string{"Hello"}}), ctx))
+		for _, event := range events {
+			assert.IsType(t, provider.StreamEventChunk{}, event, "unexpected event after the cancellation")
+		}
+	})
+}
+
+func start(t *testing.T, p provider.Provider, ctx context.Context) <-chan provider.StreamingEvent {
+	t.Helper()
+
+	events, err := p.CompleteStreaming(ctx, messages)
+	require.NoError(t, err)
+	require.NotNil(t, events)
+
+	return events
+}
+
+// collect receives the events until the channel is closed, failing if it
+// isn't closed in time.
+func collect(t *testing.T, events <-chan provider.StreamingEvent) []provider.StreamingEvent {
+	t.Helper()
+
+	var result []provider.StreamingEvent
+	timeout := time.After(closeTimeout)
+	for {
+		select {
+		case event, ok := <-events:
+			if !ok {
+				return result
+			}
+			result = append(result, event)
+		case <-timeout:
+			require.FailNow(t, "the channel wasn't closed", "received %d events", len(result))
+		}
+	}
+}
+
+// requireError checks that event is an error matching target, or any error
+// when target is nil.
+func requireError(t *testing.T, event provider.StreamingEvent, target error) {
+	t.Helper()
+
+	e, ok := event.(provider.StreamEventError)
+	require.True(t, ok, "expected an error, got %#v", event)
+	require.Error(t, e.Error)
+	if target != nil {
+		assert.ErrorIs(t, e.Error, target)
+	}
+}
@@ -0,0 +1,140 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"time"
+
+	"mark/internal/llm"
+)
+
+// StreamEventRetry is sent when a request failed before streaming anything
+// and is sent again after Delay.
+type StreamEventRetry struct {
+	Attempt     int // the attempt about to be made, 2 for the first retry
+	MaxAttempts int
+	Delay       time.Duration
+	Err         error // error of the failed attempt
+}
+
+// RetryPolicy configures how requests are retried.
+type RetryPolicy struct {
+	// MaxRetries is the number of retries after the first attempt.
+	MaxRetries int
+	// BaseDelay is the delay before the first retry, doubled for each
+	// retry.
+	BaseDelay time.Duration
+	// MaxDelay caps the delay between attempts, unless the API asks to wait
+	// longer.
+	MaxDelay time.Duration
+}
+
+// DefaultRetryPolicy returns the policy used unless configured otherwise.
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxRetries: 3,
+		BaseDelay:  time.Second,
+		MaxDelay:   30 * time.Second,
+	}
+}
+
+// delay returns how long to wait before the retry following attempt. The
+// delay asked by the API is honored, otherwise the delay grows
+// exponentially, with jitter so clients don't retry in sync.
+func (policy RetryPolicy) delay(attempt int, err error) time.Duration {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) && apiErr.RetryAfter > 0 {
+		return apiErr.RetryAfter
+	}
+
+	delay := policy.BaseDelay << (attempt - 1)
+	if delay <= 0 || delay > policy.MaxDelay {
+		delay = policy.MaxDelay
+	}
+
+	// between half and all of the delay
+	return delay/2 + rand.N(delay/2+1)
+}
+
+// Retrying is a provider retrying the requests of another provider. Only
+// failures happening before anything is streamed are retried, so the reply
+// is never repeated.
+type Retrying struct {
+	provider Provider
+	policy   RetryPolicy
+}
+
+// WithRetry returns p retrying failed requests according to policy.
+func WithRetry(p Provider, policy RetryPolicy) *Retrying {
+	return &Retrying{provider: p, policy: policy}
+}
+
+func (r *Retrying) Name() string {
+	return r.provider.Name()
+}
+
+func (r *Retrying) Model() string {
+	return r.provider.Model()
+}
+
+func (r *Retrying) CompleteStreaming(ctx context.Context, messages []llm.Message) (<-chan StreamingEvent, error) {
+	events := make(chan StreamingEvent)
+	maxAttempts := max(r.policy.MaxRetries, 0) + 1
+
+	go func() {
+		defer close(events)
+
+		for attempt := 1; ; attempt++ {
+			err := r.attempt(ctx, messages, events)
+			if err == nil || ctx.Err() != nil {
+				return
+			}
+
+			if attempt >= maxAttempts || !Retryable(err) {
+				if attempt > 1 {
+					err = &RetryError{Attempts: attempt, Err: err}
+				}
+				Send(ctx, events, StreamEventError{Error: err})
+				return
+			}
+
+			delay := r.policy.delay(attempt, err)
+			retry := StreamEventRetry{Attempt: attempt + 1, MaxAttempts: maxAttempts, Delay: delay, Err: err}
+			if !Send(ctx, events, retry) {
+				return
+			}
+
+			select {
+			case <-time.After(delay):
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return events, nil
+}
+
+// attempt sends the request once, forwarding its events. Returns the error
+// of a request failing before streaming anything, which isn't forwarded.
+func (r *Retrying) attempt(ctx context.Context, messages []llm.Message, events chan<- StreamingEvent) error {
+	attemptEvents, err := r.provider.CompleteStreaming(ctx, messages)
+	if err != nil {
+		return err
+	}
+
+	streamed := false
+	for event := range attemptEvents {
+		if e, ok := event.(StreamEventError); ok && !streamed {
+			return e.Error
+		}
+
+		streamed = true
+		if !Send(ctx, events, event) {
+			return nil
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,154 @@
+package provider_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"mark/internal/llm/provider"
+	"mark/internal/llm/provider/providertest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var fastRetries = provider.RetryPolicy{MaxRetries: 3, BaseDelay: time.Millisecond, MaxDelay: 4 * time.Millisecond}
+
+func apiError(status int, retryAfter time.Duration) *provider.APIError {
+	return &provider.APIError{StatusCode: status, RetryAfter: retryAfter, Err: errors.New(http.StatusText(status))}
+}
+
+// receive returns the events of a request to p.
+func receive(t *testing.T, p provider.Provider) []provider.StreamingEvent {
+	t.Helper()
+
+	events, err := p.CompleteStreaming(context.Background(), nil)
+	require.NoError(t, err)
+
+	var result []provider.StreamingEvent
+	for event := range events {
+		result = append(result, event)
+	}
+	return result
+}
+
+func TestRetrying(t *testing.T) {
+	t.Parallel()
+
+	t.Run("follows the provider contract", func(t *testing.T) {
+		t.Parallel()
+
+		providertest.Run(t, func(t *testing.T, script providertest.Script) provider.Provider {
+			return provider.WithRetry(providertest.NewScripted(script), fastRetries)
+		})
+	})
+
+	t.Run("retries until the request succeeds", func(t *testing.T) {
+		t.Parallel()
+
+		scripted := providertest.NewScripted(
+			providertest.Script{Err: apiError(http.StatusTooManyRequests, 0)},
+			providertest.Script{Err: apiError(http.StatusServiceUnavailable, 0)},
+			providertest.Script{Chunks: []string{"Hello"}},
+		)
+		events := receive(t, provider.WithRetry(scripted, fastRetries))
+
+		require.Len(t, events, 4)
+		assert.Equal(t, 2, events[0].(provider.StreamEventRetry).Attempt)
+		assert.ErrorIs(t, events[0].(provider.StreamEventRetry).Err, provider.ErrRateLimited)
+		assert.Equal(t, 3, events[1].(provider.StreamEventRetry).Attempt)
+		assert.ErrorIs(t, events[1].(provider.StreamEventRetry).Err, provider.ErrUnavailable)
+		assert.Equal(t, provider.StreamEventChunk{Chunk: "Hello"}, events[2])
+		assert.Equal(t, provider.StreamEventEnd{Message: "Hello"}, events[3])
+		assert.Equal(t, 3, scripted.Requests())
+	})
+
+	t.Run("gives up after the limit", func(t *testing.T) {
+		t.Parallel()
+
+		scripted := providertest.NewScripted(providertest.Script{Err: apiError(http.StatusBadGateway, 0)})
+		events := receive(t, provider.WithRetry(scripted, fastRetries))
+
+		require.Len(t, events, 4)
+		e, ok := events[3].(provider.StreamEventError)
+		require.True(t, ok)
+
+		var retryErr *provider.RetryError
+		require.ErrorAs(t, e.Error, &retryErr)
+		assert.Equal(t, 4, retryErr.Attempts)
+		assert.ErrorIs(t, e.Error, provider.ErrUnavailable)
+		assert.Equal(t, 4, scripted.Requests())
+	})
+
+	t.Run("doesn't retry client errors", func(t *testing.T) {
+		t.Parallel()
+
+		err := apiError(http.StatusUnauthorized, 0)
+		scripted := providertest.NewScripted(providertest.Script{Err: err})
+		events := receive(t, provider.WithRetry(scripted, fastRetries))
+
+		assert.Equal(t, []provider.StreamingEvent{provider.StreamEventError{Error: err}}, events)
+		assert.Equal(t, 1, scripted.Requests())
+	})
+
+	t.Run("doesn't retry after streaming", func(t *testing.T) {
+		t.Parallel()
+
+		err := apiError(http.StatusInternalServerError, 0)
+		scripted := providertest.NewScripted(providertest.Script{Chunks: []string{"Hel"}, Err: err})
+		events := receive(t, provider.WithRetry(scripted, fastRetries))
+
+		assert.Equal(t, []provider.StreamingEvent{
+			provider.StreamEventChunk{Chunk: "Hel"},
+			provider.StreamEventError{Error: err},
+		}, events)
+	})
+
+	t.Run("honors the delay asked by the API", func(t *testing.T) {
+		t.Parallel()
+
+		scripted := providertest.NewScripted(
+			providertest.Script{Err: apiError(http.StatusTooManyRequests, 20*time.Millisecond)},
+			providertest.Script{Chunks: []string{"Hello"}},
+		)
+
+		start := time.Now()
+		events := receive(t, provider.WithRetry(scripted, fastRetries))
+
+		assert.Equal(t, 20*time.Millisecond, events[0].(provider.StreamEventRetry).Delay)
+		assert.GreaterOrEqual(t, time.Since(start), 20*time.Millisecond)
+	})
+
+	t.Run("backs off exponentially with jitter", func(t *testing.T) {
+		t.Parallel()
+
+		policy := provider.RetryPolicy{MaxRetries: 3, BaseDelay: time.Millisecond, MaxDelay: 3 * time.Millisecond}
+		scripted := providertest.NewScripted(providertest.Script{Err: apiError(http.StatusInternalServerError, 0)})
+		events := receive(t, provider.WithRetry(scripted, policy))
+
+		// 1ms, 2ms and 4ms capped to 3ms, each between half and all of it
+		for i, maxDelay := range []time.Duration{time.Millisecond, 2 * time.Millisecond, 3 * time.Millisecond} {
+			delay := events[i].(provider.StreamEventRetry).Delay
+			assert.GreaterOrEqual(t, delay, maxDelay/2)
+			assert.LessOrEqual(t, delay, maxDelay)
+		}
+	})
+
+	t.Run("stops waiting when cancelled", func(t *testing.T) {
+		t.Parallel()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		policy := provider.RetryPolicy{MaxRetries: 1, BaseDelay: time.Hour, MaxDelay: time.Hour}
+		scripted := providertest.NewScripted(providertest.Script{Err: apiError(http.StatusInternalServerError, 0)})
+
+		events, err := provider.WithRetry(scripted, policy).CompleteStreaming(ctx, nil)
+		require.NoError(t, err)
+		assert.IsType(t, provider.StreamEventRetry{}, <-events)
+
+		cancel()
+		_, open := <-events
+		assert.False(t, open)
+	})
+}
@@ -2,34 +2,72 @@ package providers
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
 
 	"mark/internal/llm"
 	"mark/internal/llm/provider"
 	"mark/internal/logging"
 
 	"github.com/openai/openai-go"
+	"github.com/openai/openai-go/option"
 )
 
 type OpenAI struct {
-	client openai.Client
-	logger *slog.Logger
+	client  openai.Client
+	options []option.RequestOption // given to NewOpenAIClient
+	model   openai.ChatModel
+	logger  *slog.Logger
 }
 
-func NewOpenAIClient() *OpenAI {
+// NewOpenAIClient creates an OpenAI provider. The client is configured from
+// the environment, like OPENAI_API_KEY, and options. Requests aren't retried
+// by the client, see provider.WithRetry.
+func NewOpenAIClient(options ...option.RequestOption) *OpenAI {
+	clientOptions := append([]option.RequestOption{option.WithMaxRetries(0)}, options...)
+
 	return &OpenAI{
-		client: openai.NewClient(),
-		logger: logging.NewLogger("provider-openai"),
+		client:  openai.NewClient(clientOptions...),
+		options: options,
+		model:   openai.ChatModelGPT4o,
+		logger:  logging.NewLogger("provider-openai"),
 	}
 }
 
+// SetModel sets the model used for completions.
+func (a *OpenAI) SetModel(model string) {
+	a.model = model
+}
+
+// WithAPIKey returns a copy of the provider authenticating with key.
+func (a *OpenAI) WithAPIKey(key string) provider.Provider {
+	p := NewOpenAIClient(append(slices.Clone(a.options), option.WithAPIKey(key))...)
+	p.model = a.model
+	return p
+}
+
+func (a *OpenAI) Name() string {
+	return "openai"
+}
+
+func (a *OpenAI) Model() string {
+	return a.model
+}
+
 func convertMessages(messages []llm.Message) []openai.ChatCompletionMessageParamUnion {
 	var chatMessages []openai.ChatCompletionMessageParamUnion
 
 	for _, msg := range messages {
-		if msg.Role == llm.RoleUser {
+		switch msg.Role {
+		case llm.RoleUser:
 			chatMessages = append(chatMessages, openai.UserMessage(msg.Content))
-		} else {
+		case llm.RoleSystem:
+			chatMessages = append(chatMessages, openai.SystemMessage(msg.Content))
+		default:
 			chatMessages = append(chatMessages, openai.AssistantMessage(msg.Content))
 		}
 	}
@@ -43,13 +81,20 @@ func (a *OpenAI) CompleteStreaming(ctx context.Context, messages []llm.Message)
 	eventCh := make(chan provider.StreamingEvent)
 
 	go func() {
+		defer close(eventCh)
+
 		messages := convertMessages(messages)
 
 		stream := a.client.Chat.Completions.NewStreaming(ctx, openai.ChatCompletionNewParams{
 			Messages: messages,
 			Seed:     openai.Int(1),
-			Model:    openai.ChatModelGPT4o,
+			Model:    a.model,
+			// the usage is sent in a last chunk without choices
+			StreamOptions: openai.ChatCompletionStreamOptionsParam{
+				IncludeUsage: openai.Bool(true),
+			},
 		})
+		defer stream.Close()
 
 		acc := openai.ChatCompletionAccumulator{}
 
@@ -64,28 +109,75 @@ func (a *OpenAI) CompleteStreaming(ctx context.Context, messages []llm.Message)
 			// it's best to use chunks after handling JustFinished events
 			if len(chunk.Choices) > 0 {
 				content := chunk.Choices[0].Delta.Content
-				if content != "" {
-					eventCh <- provider.StreamEventChunk{Chunk: content}
+				if content != "" && !provider.Send(ctx, eventCh, provider.StreamEventChunk{Chunk: content}) {
+					a.logger.Info("Streaming canceled")
+					return
 				}
 			}
 		}
 
 		if err := stream.Err(); err != nil {
-			if err == context.Canceled {
+			if ctx.Err() != nil {
 				a.logger.Info("Streaming canceled")
 				return
-			} else {
-				a.logger.Error("Streaming error", slog.String("error", err.Error()))
-				eventCh <- provider.StreamEventError{Error: err}
-				return
 			}
+
+			a.logger.Error("Streaming error", slog.String("error", err.Error()))
+			provider.Send(ctx, eventCh, provider.StreamEventError{Error: apiError(err)})
+			return
+		}
+
+		if len(acc.Choices) == 0 || acc.Choices[0].Message.Content == "" {
+			err := provider.ErrEmptyResponse
+			if len(acc.Choices) > 0 && acc.Choices[0].Message.Refusal != "" {
+				err = fmt.Errorf("%w, the model refused: %s", err, acc.Choices[0].Message.Refusal)
+			}
+
+			a.logger.Error("Streaming error", slog.String("error", err.Error()))
+			provider.Send(ctx, eventCh, provider.StreamEventError{Error: err})
+			return
 		}
 
-		response := acc.Choices[0].Message.Content
-		eventCh <- provider.StreamEventEnd{Message: response}
+		provider.Send(ctx, eventCh, provider.StreamEventEnd{
+			Message: acc.Choices[0].Message.Content,
+			Usage: provider.Usage{
+				InputTokens:  int(acc.Usage.PromptTokens),
+				OutputTokens: int(acc.Usage.CompletionTokens),
+			},
+		})
 
 		a.logger.Info("Streaming finished")
 	}()
 
 	return eventCh, nil
 }
+
+// apiError converts the error responses of the API to provider.APIError,
+// so they can be classified and retried.
+func apiError(err error) error {
+	var apiErr *openai.Error
+	if !errors.As(err, &apiErr) || apiErr.Response == nil {
+		return err
+	}
+
+	var class error
+	switch {
+	case apiErr.Code == "context_length_exceeded":
+		class = provider.ErrContextTooLong
+	case apiErr.StatusCode == http.StatusUnauthorized && apiErr.Request != nil && !hasAPIKey(apiErr.Request):
+		class = provider.ErrMissingAPIKey
+	}
+
+	return &provider.APIError{
+		StatusCode: apiErr.StatusCode,
+		RetryAfter: provider.ParseRetryAfter(apiErr.Response.Header),
+		Class:      class,
+		Err:        err,
+	}
+}
+
+// hasAPIKey checks if a request was sent with an API key.
+func hasAPIKey(request *http.Request) bool {
+	key, _ := strings.CutPrefix(request.Header.Get("Authorization"), "Bearer")
+	return strings.TrimSpace(key) != ""
+}
@@ -0,0 +1,187 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"mark/internal/llm/provider"
+	"mark/internal/llm/provider/providertest"
+
+	"github.com/openai/openai-go/option"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// serveScript serves the chat completions API, streaming script as server
+// sent events.
+func serveScript(t *testing.T, script providertest.Script) *httptest.Server {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/chat/completions" {
+			http.NotFound(w, r)
+			return
+		}
+
+		if script.Err != nil && len(script.Chunks) == 0 {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprintf(w, `{"error":{"message":%q,"type":"invalid_request_error"}}`, script.Err.Error())
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		flusher := w.(http.Flusher)
+
+		send := func(data any) {
+			encoded, err := json.Marshal(data)
+			if err != nil {
+				panic(err)
+			}
+			fmt.Fprintf(w, "data: %s\n\n", encoded)
+			flusher.Flush()
+		}
+
+		for _, chunk := range script.Chunks {
+			select {
+			case <-time.After(script.Delay):
+			case <-r.Context().Done():
+				return
+			}
+			send(completionChunk([]any{map[string]any{
+				"index":         0,
+				"delta":         map[string]any{"role": "assistant", "content": chunk},
+				"finish_reason": nil,
+			}}, nil))
+		}
+
+		switch {
+		case script.Hang:
+			<-r.Context().Done()
+			return
+		case script.Err != nil:
+			send(map[string]any{"error": map[string]any{"message": script.Err.Error()}})
+			return
+		}
+
+		send(completionChunk([]any{map[string]any{
+			"index":         0,
+			"delta":         map[string]any{},
+			"finish_reason": "stop",
+		}}, nil))
+		send(completionChunk([]any{}, map[string]any{
+			"prompt_tokens":     script.Usage.InputTokens,
+			"completion_tokens": script.Usage.OutputTokens,
+			"total_tokens":      script.Usage.InputTokens + script.Usage.OutputTokens,
+		}))
+		fmt.Fprint(w, "data: [DONE]\n\n")
+		flusher.Flush()
+	}))
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+func completionChunk(choices []any, usage any) map[string]any {
+	return map[string]any{
+		"id":      "chatcmpl-test",
+		"object":  "chat.completion.chunk",
+		"created": 0,
+		"model":   "gpt-4o",
+		"choices": choices,
+		"usage":   usage,
+	}
+}
+
+func TestOpenAI(t *testing.T) {
+	t.Parallel()
+
+	providertest.Run(t, func(t *testing.T, script providertest.Script) provider.Provider {
+		server := serveScript(t, script)
+		return NewOpenAIClient(
+			option.WithBaseURL(server.URL),
+			option.WithAPIKey("test"),
+			option.WithMaxRetries(0),
+		)
+	})
+}
+
+// requestError returns the error of a request to an API replying with
+// status and body.
+func requestError(t *testing.T, status int, header http.Header, body string, options ...option.RequestOption) error {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for name, values := range header {
+			w.Header()[name] = values
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		fmt.Fprint(w, body)
+	}))
+	t.Cleanup(server.Close)
+
+	options = append([]option.RequestOption{option.WithBaseURL(server.URL), option.WithAPIKey("test")}, options...)
+	events, err := NewOpenAIClient(options...).CompleteStreaming(context.Background(), nil)
+	require.NoError(t, err)
+
+	event := <-events
+	e, ok := event.(provider.StreamEventError)
+	require.True(t, ok, "expected an error, got %#v", event)
+
+	return e.Error
+}
+
+func TestOpenAIErrors(t *testing.T) {
+	t.Parallel()
+
+	t.Run("rate limited", func(t *testing.T) {
+		t.Parallel()
+
+		err := requestError(t, http.StatusTooManyRequests, http.Header{"Retry-After": {"3"}},
+			`{"error":{"message":"slow down","type":"rate_limit_error"}}`)
+
+		var apiErr *provider.APIError
+		require.ErrorAs(t, err, &apiErr)
+		assert.Equal(t, http.StatusTooManyRequests, apiErr.StatusCode)
+		assert.Equal(t, 3*time.Second, apiErr.RetryAfter)
+		assert.ErrorIs(t, err, provider.ErrRateLimited)
+		assert.True(t, provider.Retryable(err))
+	})
+
+	t.Run("missing API key", func(t *testing.T) {
+		t.Parallel()
+
+		err := requestError(t, http.StatusUnauthorized, nil,
+			`{"error":{"message":"You didn't provide an API key.","type":"invalid_request_error"}}`,
+			option.WithAPIKey(""))
+
+		assert.ErrorIs(t, err, provider.ErrMissingAPIKey)
+		assert.ErrorIs(t, err, provider.ErrUnauthorized)
+	})
+
+	t.Run("invalid API key", func(t *testing.T) {
+		t.Parallel()
+
+		err := requestError(t, http.StatusUnauthorized, nil,
+			`{"error":{"message":"Incorrect API key provided.","type":"invalid_request_error","code":"invalid_api_key"}}`)
+
+		assert.ErrorIs(t, err, provider.ErrUnauthorized)
+		assert.NotErrorIs(t, err, provider.ErrMissingAPIKey)
+	})
+
+	t.Run("context too long", func(t *testing.T) {
+		t.Parallel()
+
+		err := requestError(t, http.StatusBadRequest, nil,
+			`{"error":{"message":"maximum context length exceeded","type":"invalid_request_error","code":"context_length_exceeded"}}`)
+
+		assert.ErrorIs(t, err, provider.ErrContextTooLong)
+		assert.False(t, provider.Retryable(err))
+	})
+}
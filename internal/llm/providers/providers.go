@@ -0,0 +1,33 @@
+// Package providers implements the LLM providers.
+package providers
+
+import (
+	"fmt"
+
+	"mark/internal/llm/provider"
+
+	"github.com/openai/openai-go/option"
+)
+
+// Names returns the names of the supported providers.
+func Names() []string {
+	return []string{"openai"}
+}
+
+// New returns the provider with the name, using model. A non empty baseURL
+// replaces the URL of the API, to use a compatible server.
+func New(name, model, baseURL string) (provider.Provider, error) {
+	switch name {
+	case "openai":
+		var options []option.RequestOption
+		if baseURL != "" {
+			options = append(options, option.WithBaseURL(baseURL))
+		}
+
+		p := NewOpenAIClient(options...)
+		p.SetModel(model)
+		return p, nil
+	default:
+		return nil, fmt.Errorf("unknown provider %q", name)
+	}
+}
@@ -0,0 +1,31 @@
+package providers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNew(t *testing.T) {
+	t.Parallel()
+
+	t.Run("openai", func(t *testing.T) {
+		t.Parallel()
+
+		p, err := New("openai", "gpt-4o-mini", "http://localhost:8080/v1")
+		require.NoError(t, err)
+		assert.Equal(t, "openai", p.Name())
+		assert.Equal(t, "gpt-4o-mini", p.Model())
+
+		keyed := p.(*OpenAI).WithAPIKey("sk-test")
+		assert.Equal(t, "gpt-4o-mini", keyed.Model(), "the model is kept with a new key")
+	})
+
+	t.Run("unknown provider", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := New("nope", "model", "")
+		assert.EqualError(t, err, `unknown provider "nope"`)
+	})
+}
@@ -26,3 +26,9 @@ func Setup() {
 		log.SetOutput(io.Discard)
 	}
 }
+
+// SetupStderr logs to stderr. Used when running without a terminal UI, where
+// stderr is free to report what is happening.
+func SetupStderr() {
+	log.SetOutput(os.Stderr)
+}
@@ -0,0 +1,96 @@
+package markdown
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Blocks splits text into its top level blocks, like paragraphs, lists and
+// code blocks. A block ends at a blank line followed by a line that isn't
+// indented, outside of code blocks. Indented lines continue the block, like
+// the paragraphs of a list item.
+//
+// Blocks only depend on the text before them and the first line after
+// them, so appending to text only changes the last block or adds new ones.
+func Blocks(text string) []string {
+	lines := strings.Split(text, "\n")
+
+	var blocks []string
+	start := 0
+	content := false // the current block has a non blank line
+	blank := false   // the previous line is blank
+	var open *fence
+
+	for i, line := range lines {
+		if open != nil {
+			if isClosingFence(line, *open) {
+				open = nil
+			}
+			continue
+		}
+
+		if strings.TrimSpace(line) == "" {
+			blank = true
+			continue
+		}
+
+		if blank && content && !isIndented(line) {
+			blocks = append(blocks, strings.Join(lines[start:i], "\n"))
+			start = i
+		}
+		blank = false
+		content = true
+
+		if f, _, ok := parseFence(line); ok {
+			open = &f
+		}
+	}
+
+	if content {
+		blocks = append(blocks, strings.Join(lines[start:], "\n"))
+	}
+
+	return blocks
+}
+
+func isIndented(line string) bool {
+	return strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")
+}
+
+// linkDefinitionPattern matches a link reference definition, like
+// "[1]: https://example.com".
+var linkDefinitionPattern = regexp.MustCompile(`^ {0,3}\[[^\]]+\]:\s*\S`)
+
+// linkDefinitions returns the link reference definitions of text, one per
+// line. References can use the definitions of any block, so they must be
+// rendered with every block. Definitions can't interrupt a paragraph, so
+// only the lines after a blank line or another definition are considered.
+func linkDefinitions(text string) string {
+	var definitions []string
+	var open *fence
+	start := true // the line can start a definition
+
+	for _, line := range strings.Split(text, "\n") {
+		if open != nil {
+			if isClosingFence(line, *open) {
+				open = nil
+			}
+			continue
+		}
+
+		if f, _, ok := parseFence(line); ok {
+			open = &f
+			start = false
+			continue
+		}
+
+		if start && linkDefinitionPattern.MatchString(line) {
+			definitions = append(definitions, strings.TrimSpace(line))
+			continue
+		}
+
+		start = strings.TrimSpace(line) == ""
+	}
+
+	return strings.Join(definitions, "\n")
+}
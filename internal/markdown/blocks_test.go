@@ -0,0 +1,55 @@
+package markdown
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBlocks(t *testing.T) {
+	t.Parallel()
+
+	t.Run("splits at blank lines", func(t *testing.T) {
+		t.Parallel()
+
+		text := "# Title\n\nSome\ntext\n\n\n- a\n- b\n"
+		assert.Equal(t, []string{"# Title\n", "Some\ntext\n\n", "- a\n- b\n"}, Blocks(text))
+	})
+
+	t.Run("keeps code blocks whole", func(t *testing.T) {
+		t.Parallel()
+
+		text := "```go\nx\n\ny\n```\n\nafter"
+		assert.Equal(t, []string{"```go\nx\n\ny\n```\n", "after"}, Blocks(text))
+	})
+
+	t.Run("indented lines continue the block", func(t *testing.T) {
+		t.Parallel()
+
+		text := "- item\n\n  more about the item\n\nafter"
+		assert.Equal(t, []string{"- item\n\n  more about the item\n", "after"}, Blocks(text))
+	})
+
+	t.Run("unclosed code block", func(t *testing.T) {
+		t.Parallel()
+
+		assert.Equal(t, []string{"text\n", "```\ncode\n\nmore"}, Blocks("text\n\n```\ncode\n\nmore"))
+	})
+
+	t.Run("blank text", func(t *testing.T) {
+		t.Parallel()
+
+		assert.Empty(t, Blocks(""))
+		assert.Empty(t, Blocks("\n  \n"))
+		assert.Equal(t, []string{"\n\ntext"}, Blocks("\n\ntext"))
+	})
+}
+
+func TestLinkDefinitions(t *testing.T) {
+	t.Parallel()
+
+	text := "[1]: https://a.example\ntext\n[2]: not a definition, it would continue the paragraph\n\n" +
+		"```\n[3]: https://code.example\n```\n\n  [4]:  https://d.example \"title\""
+	assert.Equal(t, "[1]: https://a.example\n[4]:  https://d.example \"title\"", linkDefinitions(text))
+	assert.Empty(t, linkDefinitions("no definitions"))
+}
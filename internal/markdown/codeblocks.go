@@ -0,0 +1,143 @@
+// Package markdown renders the markdown written by the agent and extracts
+// content from it.
+package markdown
+
+import (
+	"strings"
+)
+
+// CodeBlock is a fenced code block.
+type CodeBlock struct {
+	// Language is the first word of the info string, like "go".
+	Language string
+	// Info is the whole info string following the opening fence.
+	Info string
+	// Code is the content of the block, each line ending with a newline.
+	Code string
+	// Filename is the path the block is meant for, when hinted in the info
+	// string, the first line of the code or the text before the block.
+	Filename string
+}
+
+// FirstLine returns the first non blank line of the code.
+func (b CodeBlock) FirstLine() string {
+	for _, line := range strings.Split(b.Code, "\n") {
+		if strings.TrimSpace(line) != "" {
+			return strings.TrimSpace(line)
+		}
+	}
+	return ""
+}
+
+// fence is the opening fence of a code block.
+type fence struct {
+	char   byte
+	length int
+	indent int
+}
+
+// CodeBlocks returns the fenced code blocks of text, in order. A block that
+// isn't closed runs until the end of text, like in a reply that is still
+// being written.
+func CodeBlocks(text string) []CodeBlock {
+	var blocks []CodeBlock
+
+	var open *fence
+	var block CodeBlock
+	var code strings.Builder
+	var previous string // last non blank line of text before the block
+
+	for _, line := range strings.Split(text, "\n") {
+		if open == nil {
+			f, info, ok := parseFence(line)
+			if !ok {
+				if strings.TrimSpace(line) != "" {
+					previous = line
+				}
+				continue
+			}
+
+			open = &f
+			block = CodeBlock{Info: info}
+			if fields := strings.Fields(info); len(fields) > 0 {
+				block.Language, _, _ = strings.Cut(fields[0], ":")
+			}
+			block.Filename = previous // replaced by better hints when closed
+			code.Reset()
+			continue
+		}
+
+		if isClosingFence(line, *open) {
+			block.Code = code.String()
+			block.Filename = filenameHint(block.Info, block.Code, block.Filename)
+			blocks = append(blocks, block)
+			open = nil
+			previous = ""
+			continue
+		}
+
+		code.WriteString(removeIndent(line, open.indent))
+		code.WriteString("\n")
+	}
+
+	if open != nil {
+		// the text usually ends with a newline that isn't part of the code
+		if trimmed := strings.TrimRight(code.String(), "\n"); trimmed != "" {
+			block.Code = trimmed + "\n"
+		}
+		block.Filename = filenameHint(block.Info, block.Code, block.Filename)
+		blocks = append(blocks, block)
+	}
+
+	return blocks
+}
+
+// parseFence parses an opening fence: up to 3 spaces, then at least 3
+// backticks or tildes, then the info string.
+func parseFence(line string) (fence, string, bool) {
+	indent := len(line) - len(strings.TrimLeft(line, " "))
+	if indent > 3 {
+		return fence{}, "", false
+	}
+
+	rest := line[indent:]
+	if rest == "" || (rest[0] != '`' && rest[0] != '~') {
+		return fence{}, "", false
+	}
+
+	char := rest[0]
+	length := len(rest) - len(strings.TrimLeft(rest, string(char)))
+	if length < 3 {
+		return fence{}, "", false
+	}
+
+	info := strings.TrimSpace(rest[length:])
+	if char == '`' && strings.Contains(info, "`") {
+		return fence{}, "", false // an inline code span
+	}
+
+	return fence{char: char, length: length, indent: indent}, info, true
+}
+
+// isClosingFence checks if line closes the block opened with f.
+func isClosingFence(line string, f fence) bool {
+	indent := len(line) - len(strings.TrimLeft(line, " "))
+	if indent > 3 {
+		return false
+	}
+
+	rest := strings.TrimRight(line[indent:], " \t")
+	if len(rest) < f.length {
+		return false
+	}
+
+	return strings.Trim(rest, string(f.char)) == ""
+}
+
+// removeIndent removes up to indent leading spaces from line.
+func removeIndent(line string, indent int) string {
+	for i := 0; i < indent && strings.HasPrefix(line, " "); i++ {
+		line = line[1:]
+	}
+	return line
+}
@@ -0,0 +1,63 @@
+package markdown
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCodeBlocks(t *testing.T) {
+	t.Parallel()
+
+	t.Run("fenced blocks", func(t *testing.T) {
+		t.Parallel()
+
+		text := "Some text\n\n```go\nfunc main() {\n}\n```\n\nMore text\n\n~~~\nplain\n~~~\n"
+
+		assert.Equal(t, []CodeBlock{
+			{Language: "go", Info: "go", Code: "func main() {\n}\n"},
+			{Code: "plain\n"},
+		}, CodeBlocks(text))
+	})
+
+	t.Run("info string", func(t *testing.T) {
+		t.Parallel()
+
+		blocks := CodeBlocks("```python title=\"main.py\"\nprint()\n```")
+		assert.Equal(t, []CodeBlock{{Language: "python", Info: "python title=\"main.py\"", Code: "print()\n", Filename: "main.py"}}, blocks)
+	})
+
+	t.Run("nested fences", func(t *testing.T) {
+		t.Parallel()
+
+		text := "````markdown\n```go\nx\n```\n````\n"
+		assert.Equal(t, []CodeBlock{{Language: "markdown", Info: "markdown", Code: "```go\nx\n```\n"}}, CodeBlocks(text))
+	})
+
+	t.Run("indented fence", func(t *testing.T) {
+		t.Parallel()
+
+		text := "  ```sh\n  ls\n    -l\n  ```\n"
+		assert.Equal(t, []CodeBlock{{Language: "sh", Info: "sh", Code: "ls\n  -l\n"}}, CodeBlocks(text))
+	})
+
+	t.Run("not a fence", func(t *testing.T) {
+		t.Parallel()
+
+		assert.Empty(t, CodeBlocks("``not a fence``\n``\n    ```\n"))
+	})
+
+	t.Run("unclosed block", func(t *testing.T) {
+		t.Parallel()
+
+		assert.Equal(t, []CodeBlock{{Language: "go", Info: "go", Code: "x := 1\n"}}, CodeBlocks("```go\nx := 1\n"))
+		assert.Equal(t, []CodeBlock{{Language: "go", Info: "go", Code: "x := 1\n"}}, CodeBlocks("```go\nx := 1"))
+	})
+}
+
+func TestCodeBlock_FirstLine(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, "package main", CodeBlock{Code: "\n  package main\n\nfunc main() {}\n"}.FirstLine())
+	assert.Equal(t, "", CodeBlock{}.FirstLine())
+}
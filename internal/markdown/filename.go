@@ -0,0 +1,72 @@
+package markdown
+
+import (
+	"path"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+var (
+	// infoAttributeRegexp matches attributes naming the file in the info
+	// string, like title="main.go".
+	infoAttributeRegexp = regexp.MustCompile(`\b(?:title|file|filename|path)=["']?([^"'\s]+)`)
+
+	// commentRegexp matches a comment on the first line of the code naming
+	// the file, like "// main.go" or "# file: setup.py".
+	commentRegexp = regexp.MustCompile(`^\s*(?://|#|--|;|/\*|<!--)\s*(?:(?i:file(?:name)?|path):\s*)?(\S+?)\s*(?:\*/|-->)?\s*$`)
+
+	// textPathRegexp matches a path quoted as code or in bold in the text,
+	// like "Create `cmd/main.go`:".
+	textPathRegexp = regexp.MustCompile("(?:`([^`\\s]+)`|\\*\\*([^*\\s]+)\\*\\*)")
+)
+
+// filenameHint finds the path a code block is meant for. In order, it looks
+// at the info string, like "go title=main.go" or "go:main.go", a comment on
+// the first line of the code and the last path mentioned in the line of
+// text before the block.
+func filenameHint(info string, code string, previous string) string {
+	if match := infoAttributeRegexp.FindStringSubmatch(info); match != nil && looksLikePath(match[1]) {
+		return match[1]
+	}
+
+	if fields := strings.Fields(info); len(fields) > 0 {
+		if _, name, ok := strings.Cut(fields[0], ":"); ok && looksLikePath(name) {
+			return name
+		}
+		if len(fields) > 1 && looksLikePath(fields[1]) {
+			return fields[1]
+		}
+	}
+
+	firstLine, _, _ := strings.Cut(code, "\n")
+	if match := commentRegexp.FindStringSubmatch(firstLine); match != nil && looksLikePath(match[1]) {
+		return match[1]
+	}
+
+	hint := ""
+	for _, match := range textPathRegexp.FindAllStringSubmatch(previous, -1) {
+		name := match[1] + match[2]
+		if looksLikePath(name) {
+			hint = name
+		}
+	}
+
+	return strings.TrimSuffix(hint, ":")
+}
+
+// looksLikePath checks if name is a file path with an extension or a
+// directory, like "main.go", "cmd/root.go" or "build/Makefile".
+func looksLikePath(name string) bool {
+	name = strings.TrimSuffix(name, ":")
+	if name == "" || strings.ContainsAny(name, "()[]{}<>\"'`=,") || strings.HasSuffix(name, "/") {
+		return false
+	}
+
+	base := path.Base(name)
+	ext := path.Ext(base)
+
+	hasExtension := len(ext) > 1 && ext != base && unicode.IsLetter(rune(ext[1]))
+
+	return hasExtension || strings.Contains(name, "/")
+}
@@ -0,0 +1,41 @@
+package markdown
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFilenameHint(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		text     string
+		expected string
+	}{
+		{"title attribute", "```go title=\"cmd/main.go\"\nx\n```", "cmd/main.go"},
+		{"file attribute", "```go file=main.go\nx\n```", "main.go"},
+		{"language and path", "```go:cmd/main.go\nx\n```", "cmd/main.go"},
+		{"path after language", "```python setup.py\nx\n```", "setup.py"},
+		{"comment", "```go\n// internal/app/app.go\npackage app\n```", "internal/app/app.go"},
+		{"comment with label", "```python\n# File: setup.py\nx\n```", "setup.py"},
+		{"block comment", "```css\n/* style.css */\nx\n```", "style.css"},
+		{"code in text", "Create `internal/util/file.go` with:\n\n```go\nx\n```", "internal/util/file.go"},
+		{"bold in text", "**main.go**\n```go\nx\n```", "main.go"},
+		{"last path in text", "Rename `a.go` to `b.go`:\n```go\nx\n```", "b.go"},
+		{"text before another block", "`a.go`\n```go\nx\n```\n```go\ny\n```", ""},
+		{"comment that isn't a path", "```go\n// do the thing\nx\n```", ""},
+		{"version in text", "Use `1.5`:\n```go\nx\n```", ""},
+		{"no hint", "```go\nx\n```", ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			blocks := CodeBlocks(test.text)
+			assert.Equal(t, test.expected, blocks[len(blocks)-1].Filename)
+		})
+	}
+}
@@ -0,0 +1,163 @@
+package markdown
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/charmbracelet/glamour"
+	"github.com/charmbracelet/glamour/styles"
+	"github.com/charmbracelet/x/ansi"
+)
+
+// renderedBlock is the output of a block, kept until the block changes.
+type renderedBlock struct {
+	source string
+	output []string // lines without the blank lines around the block
+}
+
+// Renderer renders markdown for the terminal. The blocks of the text are
+// rendered separately and cached, so rendering a reply as it's streamed
+// only renders the blocks that changed, usually the last one. The link
+// reference definitions of the text are rendered with every block, so
+// references work across blocks.
+type Renderer struct {
+	style       string
+	width       int
+	term        *glamour.TermRenderer
+	blocks      []renderedBlock
+	definitions string // link reference definitions the blocks were rendered with
+
+	// output of the last call to Render
+	text   string
+	output string
+
+	rendered int // number of blocks rendered, for tests
+}
+
+func NewRenderer() *Renderer {
+	return &Renderer{style: styles.AutoStyle, width: -1}
+}
+
+// Styles returns the names of the styles, "auto" picking a dark or light
+// style depending on the terminal.
+func Styles() []string {
+	return []string{
+		styles.AutoStyle, styles.DarkStyle, styles.LightStyle, styles.NoTTYStyle, styles.AsciiStyle,
+		styles.DraculaStyle, styles.TokyoNightStyle, styles.PinkStyle,
+	}
+}
+
+// SetStyle sets the style of the output, one of Styles. The cache is
+// dropped.
+func (r *Renderer) SetStyle(style string) {
+	r.style = style
+	r.width = -1
+}
+
+// Render renders text wrapped at width. The output is cached until the text
+// or the width change.
+func (r *Renderer) Render(text string, width int) (string, error) {
+	if width != r.width {
+		style := glamour.WithStandardStyle(r.style)
+		if r.style == styles.AutoStyle {
+			style = glamour.WithAutoStyle()
+		}
+
+		term, err := glamour.NewTermRenderer(style, glamour.WithWordWrap(width))
+		if err != nil {
+			return "", fmt.Errorf("failed to create glamour renderer: %w", err)
+		}
+
+		r.width = width
+		r.term = term
+		r.blocks = nil
+		r.text = ""
+		r.output = ""
+	} else if text == r.text {
+		return r.output, nil
+	}
+
+	// a new definition can change the output of any block
+	definitions := linkDefinitions(text)
+	if definitions != r.definitions {
+		r.blocks = nil
+		r.definitions = definitions
+	}
+
+	sources := Blocks(text)
+	blocks := make([]renderedBlock, len(sources))
+	for i, source := range sources {
+		// trailing blank lines are added as the next block is streamed
+		source = strings.TrimRight(source, "\n")
+
+		if i < len(r.blocks) && r.blocks[i].source == source {
+			blocks[i] = r.blocks[i]
+			continue
+		}
+
+		// the definitions go first, a block may be a code block still open
+		document := source
+		if definitions != "" {
+			document = definitions + "\n\n" + source
+		}
+
+		output, err := r.term.Render(document)
+		if err != nil {
+			return "", fmt.Errorf("failed to render markdown: %w", err)
+		}
+		r.rendered++
+
+		blocks[i] = renderedBlock{source: source, output: trimBlankLines(strings.Split(output, "\n"))}
+	}
+
+	r.blocks = blocks
+	r.text = text
+	r.output = joinBlocks(blocks)
+
+	return r.output, nil
+}
+
+// joinBlocks joins the output of blocks with a blank line between them,
+// like glamour renders a whole document. Blocks without output, like link
+// reference definitions, are skipped.
+func joinBlocks(blocks []renderedBlock) string {
+	blocks = slices.DeleteFunc(slices.Clone(blocks), func(block renderedBlock) bool {
+		return len(block.output) == 0
+	})
+	if len(blocks) == 0 {
+		return ""
+	}
+
+	var b strings.Builder
+	b.WriteString("\n")
+	for i, block := range blocks {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		for _, line := range block.output {
+			b.WriteString(line)
+			b.WriteString("\n")
+		}
+	}
+	b.WriteString("\n")
+
+	return b.String()
+}
+
+// trimBlankLines removes the lines without text at the start and the end
+// of lines.
+func trimBlankLines(lines []string) []string {
+	isBlank := func(line string) bool {
+		return strings.TrimSpace(ansi.Strip(line)) == ""
+	}
+
+	for len(lines) > 0 && isBlank(lines[0]) {
+		lines = lines[1:]
+	}
+	for len(lines) > 0 && isBlank(lines[len(lines)-1]) {
+		lines = lines[:len(lines)-1]
+	}
+
+	return lines
+}
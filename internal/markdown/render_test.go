@@ -0,0 +1,255 @@
+package markdown
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/charmbracelet/glamour"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const renderWidth = 60
+
+// longReply returns a reply with many blocks of different kinds.
+func longReply() string {
+	var b strings.Builder
+	for i := range 20 {
+		b.WriteString("## Step\n\n")
+		b.WriteString(strings.Repeat("Some explanation of the step, long enough to be wrapped. ", 4))
+		b.WriteString("\n\n- first point\n- second point with `code`\n\n")
+		if i%2 == 0 {
+			b.WriteString("```go\nfunc main() {\n\tfmt.Println(\"hello\")\n}\n```\n\n")
+		}
+	}
+	return b.String()
+}
+
+// referenceLinks uses link references defined in other blocks, even after
+// them, and has a definition lookalike in a code block.
+const referenceLinks = `See [the docs][1].
+
+- a [list item][guide]
+
+` + "```" + `
+[2]: https://example.com/code
+` + "```" + `
+
+[1]: https://example.com/docs
+[guide]: https://example.com/guide
+
+The end, see [the docs][1] again.
+`
+
+// glamourRender renders text with glamour as a whole, without the padding
+// at the end of the lines.
+func glamourRender(t *testing.T, text string) string {
+	t.Helper()
+
+	term, err := glamour.NewTermRenderer(glamour.WithAutoStyle(), glamour.WithWordWrap(renderWidth))
+	require.NoError(t, err)
+	output, err := term.Render(text)
+	require.NoError(t, err)
+
+	return trimSpaces(output)
+}
+
+// chunks splits text into chunks of size bytes, like a streamed reply.
+func chunks(text string, size int) []string {
+	var result []string
+	for len(text) > size {
+		result = append(result, text[:size])
+		text = text[size:]
+	}
+	return append(result, text)
+}
+
+func TestRenderer(t *testing.T) {
+	t.Parallel()
+
+	t.Run("renders like glamour", func(t *testing.T) {
+		t.Parallel()
+
+		for name, text := range map[string]string{
+			"blocks":          "# Title\n\nSome text\n\n- a\n- b\n\n```go\nx\n```\n",
+			"long reply":      longReply(),
+			"reference links": referenceLinks,
+		} {
+			output, err := NewRenderer().Render(text, renderWidth)
+			require.NoError(t, err)
+			assert.Equal(t, glamourRender(t, text), trimSpaces(output), name)
+		}
+	})
+
+	t.Run("references use the definitions of other blocks", func(t *testing.T) {
+		t.Parallel()
+
+		output, err := NewRenderer().Render(referenceLinks, renderWidth)
+		require.NoError(t, err)
+		assert.Contains(t, output, "https://example.com/docs")
+		assert.NotContains(t, output, "[the docs][1]")
+		assert.NotContains(t, output, "[1]:")
+	})
+
+	t.Run("caches the output", func(t *testing.T) {
+		t.Parallel()
+
+		renderer := NewRenderer()
+		first, err := renderer.Render("a\n\nb", renderWidth)
+		require.NoError(t, err)
+		assert.Equal(t, 2, renderer.rendered)
+
+		second, err := renderer.Render("a\n\nb", renderWidth)
+		require.NoError(t, err)
+		assert.Equal(t, first, second)
+		assert.Equal(t, 2, renderer.rendered)
+	})
+
+	t.Run("only renders the changed blocks", func(t *testing.T) {
+		t.Parallel()
+
+		renderer := NewRenderer()
+		_, err := renderer.Render("a\n\nb", renderWidth)
+		require.NoError(t, err)
+
+		_, err = renderer.Render("a\n\nbc", renderWidth)
+		require.NoError(t, err)
+		assert.Equal(t, 3, renderer.rendered)
+
+		// the blank lines ending a block don't change it
+		_, err = renderer.Render("a\n\nbc\n\n", renderWidth)
+		require.NoError(t, err)
+		assert.Equal(t, 3, renderer.rendered)
+	})
+
+	t.Run("renders everything when the width changes", func(t *testing.T) {
+		t.Parallel()
+
+		renderer := NewRenderer()
+		narrow, err := renderer.Render("a\n\nb", 20)
+		require.NoError(t, err)
+
+		wide, err := renderer.Render("a\n\nb", 40)
+		require.NoError(t, err)
+		assert.Equal(t, 4, renderer.rendered)
+		assert.NotEqual(t, narrow, wide)
+	})
+
+	t.Run("renders everything when the style changes", func(t *testing.T) {
+		t.Parallel()
+
+		renderer := NewRenderer()
+		renderer.SetStyle("dark")
+		dark, err := renderer.Render("# Title\n\nsome *text*", renderWidth)
+		require.NoError(t, err)
+
+		renderer.SetStyle("ascii")
+		ascii, err := renderer.Render("# Title\n\nsome *text*", renderWidth)
+		require.NoError(t, err)
+		assert.Equal(t, 4, renderer.rendered)
+		assert.NotEqual(t, dark, ascii)
+	})
+
+	t.Run("streamed output matches the output of the whole text", func(t *testing.T) {
+		t.Parallel()
+
+		text := longReply()
+
+		renderer := NewRenderer()
+		streamed := ""
+		for _, chunk := range chunks(text, 16) {
+			streamed += chunk
+			_, err := renderer.Render(streamed, renderWidth)
+			require.NoError(t, err)
+		}
+		output, err := renderer.Render(text, renderWidth)
+		require.NoError(t, err)
+
+		assert.Equal(t, glamourRender(t, text), trimSpaces(output))
+	})
+
+	t.Run("streamed reference links match the output of glamour", func(t *testing.T) {
+		t.Parallel()
+
+		renderer := NewRenderer()
+		streamed := ""
+		for _, chunk := range chunks(referenceLinks, 4) {
+			streamed += chunk
+			_, err := renderer.Render(streamed, renderWidth)
+			require.NoError(t, err)
+		}
+		output, err := renderer.Render(referenceLinks, renderWidth)
+		require.NoError(t, err)
+
+		assert.Equal(t, glamourRender(t, referenceLinks), trimSpaces(output))
+	})
+
+	t.Run("empty text", func(t *testing.T) {
+		t.Parallel()
+
+		output, err := NewRenderer().Render("", renderWidth)
+		require.NoError(t, err)
+		assert.Empty(t, output)
+	})
+}
+
+// trimSpaces removes the padding at the end of the lines.
+func trimSpaces(text string) string {
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, " ")
+	}
+	return strings.Join(lines, "\n")
+}
+
+// BenchmarkRenderStreaming renders a reply after each streamed chunk, as the
+// messages pane does.
+func BenchmarkRenderStreaming(b *testing.B) {
+	text := longReply()
+	parts := chunks(text, 32)
+
+	b.Run("new renderer for every chunk", func(b *testing.B) {
+		for range b.N {
+			streamed := ""
+			for _, chunk := range parts {
+				streamed += chunk
+				term, err := glamour.NewTermRenderer(glamour.WithAutoStyle(), glamour.WithWordWrap(renderWidth))
+				if err != nil {
+					b.Fatal(err)
+				}
+				if _, err := term.Render(streamed); err != nil {
+					b.Fatal(err)
+				}
+			}
+		}
+	})
+
+	b.Run("cached renderer", func(b *testing.B) {
+		for range b.N {
+			renderer := NewRenderer()
+			streamed := ""
+			for _, chunk := range parts {
+				streamed += chunk
+				if _, err := renderer.Render(streamed, renderWidth); err != nil {
+					b.Fatal(err)
+				}
+			}
+		}
+	})
+}
+
+// BenchmarkRenderUnchanged renders the same reply again, like on a keypress.
+func BenchmarkRenderUnchanged(b *testing.B) {
+	text := longReply()
+	renderer := NewRenderer()
+	if _, err := renderer.Render(text, renderWidth); err != nil {
+		b.Fatal(err)
+	}
+
+	b.ResetTimer()
+	for range b.N {
+		if _, err := renderer.Render(text, renderWidth); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -2,6 +2,8 @@ package messages
 
 import (
 	"fmt"
+	"maps"
+	"slices"
 
 	"mark/internal/app"
 
@@ -13,6 +15,7 @@ type Message struct {
 	Short            string
 	NumArgs          int
 	StdinFlagEnabled bool // Indicates if the command can read from stdin
+	PathArgs         bool // The arguments are paths, made absolute by the client
 	ToTeaMsg         func(args []string, stdin string) tea.Msg
 }
 
@@ -35,9 +38,10 @@ var Msgs map[string]Message = map[string]Message{
 		},
 	},
 	"add-context-item-file": {
-		Use:     "add-context-item-file <path>",
-		Short:   "Add a file item to the context",
-		NumArgs: 1,
+		Use:      "add-context-item-file <path>",
+		Short:    "Add a file item to the context",
+		NumArgs:  1,
+		PathArgs: true,
 		ToTeaMsg: func(args []string, stdin string) tea.Msg {
 			return app.AddContextItemFileMsg(args[0])
 		},
@@ -50,6 +54,19 @@ var Msgs map[string]Message = map[string]Message{
 			return app.RunMsg{}
 		},
 	},
+	"reply": {
+		Use:     "reply",
+		Short:   "Print the reply of the session, waiting for the current run to finish",
+		NumArgs: 0,
+		ToTeaMsg: func(args []string, stdin string) tea.Msg {
+			return app.NewReplyQueryMsg()
+		},
+	},
+}
+
+// Commands returns the names of all commands, sorted.
+func Commands() []string {
+	return slices.Sorted(maps.Keys(Msgs))
 }
 
 func ToTeaMsg(command string, args []string, stdin string) tea.Msg {
@@ -0,0 +1,80 @@
+// Package persona loads personas: named system prompts, like "reviewer" or
+// "go-expert", stored as markdown files in the personas directories of the
+// user and of the project.
+package persona
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// Ext is the extension of persona files. The name of a persona is the name
+// of its file without it.
+const Ext = ".md"
+
+// Persona is a system prompt with a name.
+type Persona struct {
+	Name   string
+	Prompt string
+	Path   string // file the persona was read from, empty for the default one
+}
+
+// Summary returns the first line of the prompt.
+func (p Persona) Summary() string {
+	summary, _, _ := strings.Cut(p.Prompt, "\n")
+	return summary
+}
+
+// Load reads the personas in dirs. A persona of a directory replaces the
+// persona with the same name in the previous ones, so the project can
+// override the personas of the user. Missing directories are skipped. The
+// personas are sorted by name.
+func Load(dirs ...string) ([]Persona, error) {
+	personas := map[string]Persona{}
+
+	for _, dir := range dirs {
+		entries, err := os.ReadDir(dir)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read personas: %w", err)
+		}
+
+		for _, entry := range entries {
+			name, ok := strings.CutSuffix(entry.Name(), Ext)
+			if !ok || name == "" || entry.IsDir() {
+				continue
+			}
+
+			path := filepath.Join(dir, entry.Name())
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read persona: %w", err)
+			}
+
+			personas[name] = Persona{Name: name, Prompt: strings.TrimSpace(string(data)), Path: path}
+		}
+	}
+
+	var sorted []Persona
+	for _, name := range slices.Sorted(maps.Keys(personas)) {
+		sorted = append(sorted, personas[name])
+	}
+	return sorted, nil
+}
+
+// Find returns the persona with the name.
+func Find(personas []Persona, name string) (Persona, bool) {
+	i := slices.IndexFunc(personas, func(p Persona) bool { return p.Name == name })
+	if i < 0 {
+		return Persona{}, false
+	}
+	return personas[i], true
+}
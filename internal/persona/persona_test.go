@@ -0,0 +1,58 @@
+package persona
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writePersonas writes the files, by name, to a new directory.
+func writePersonas(t *testing.T, files map[string]string) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	for name, content := range files {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600))
+	}
+	return dir
+}
+
+func TestLoad(t *testing.T) {
+	t.Parallel()
+
+	t.Run("later directories override earlier ones", func(t *testing.T) {
+		t.Parallel()
+
+		user := writePersonas(t, map[string]string{
+			"reviewer.md":  "Review the code.\n",
+			"go-expert.md": "You know Go.\nAnswer with idiomatic code.\n",
+			"notes.txt":    "not a persona",
+		})
+		project := writePersonas(t, map[string]string{"reviewer.md": "Review the code of this project."})
+
+		personas, err := Load(user, filepath.Join(t.TempDir(), "missing"), project)
+		require.NoError(t, err)
+
+		assert.Equal(t, []Persona{
+			{Name: "go-expert", Prompt: "You know Go.\nAnswer with idiomatic code.", Path: filepath.Join(user, "go-expert.md")},
+			{Name: "reviewer", Prompt: "Review the code of this project.", Path: filepath.Join(project, "reviewer.md")},
+		}, personas)
+		assert.Equal(t, "You know Go.", personas[0].Summary())
+	})
+
+	t.Run("find", func(t *testing.T) {
+		t.Parallel()
+
+		personas := []Persona{{Name: "reviewer"}}
+
+		p, ok := Find(personas, "reviewer")
+		assert.True(t, ok)
+		assert.Equal(t, "reviewer", p.Name)
+
+		_, ok = Find(personas, "poet")
+		assert.False(t, ok)
+	})
+}
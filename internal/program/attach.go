@@ -0,0 +1,135 @@
+package program
+
+import (
+	"os"
+	"strings"
+
+	"mark/internal/app"
+	"mark/internal/remote"
+
+	"github.com/charmbracelet/bubbles/v2/key"
+	tea "github.com/charmbracelet/bubbletea/v2"
+	"github.com/charmbracelet/x/ansi"
+)
+
+type (
+	frameMsg    string
+	detachedMsg struct{}
+)
+
+// attachModel shows the view of a running instance and forwards input to
+// it. Pressing ctrl+c detaches without stopping the instance.
+type attachModel struct {
+	attachment *remote.Attachment
+	quit       []key.Binding // bindings quitting the App, never forwarded
+	view       string
+	width      int
+	height     int
+}
+
+// Attach runs a TUI attached to the instance running in the project
+// containing the current directory, until the user detaches or the
+// instance stops. Only options.Token and the key bindings of
+// options.Config are used.
+func Attach(options Options) error {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+
+	keys, err := app.NewKeyMap(options.Config.Keys)
+	if err != nil {
+		return err
+	}
+
+	client, err := remote.NewClient(cwd)
+	if err != nil {
+		return err
+	}
+
+	attachment, err := client.Attach(options.Token)
+	if err != nil {
+		return err
+	}
+
+	m := attachModel{
+		attachment: attachment,
+		quit:       []key.Binding{keys.Main.Quit, keys.Prompt.Quit},
+	}
+
+	_, err = tea.NewProgram(m, tea.WithAltScreen(), tea.WithKeyboardEnhancements()).Run()
+
+	// stop reading frames before looking at why the session ended
+	attachment.Close()
+	if err != nil {
+		return err
+	}
+
+	return attachment.Err()
+}
+
+func (m attachModel) Init() tea.Cmd {
+	return waitForFrame(m.attachment)
+}
+
+func (m attachModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case frameMsg:
+		m.view = string(msg)
+		return m, waitForFrame(m.attachment)
+
+	case detachedMsg:
+		return m, tea.Quit
+
+	case tea.WindowSizeMsg:
+		m.width = msg.Width
+		m.height = msg.Height
+		m.attachment.Send(remote.Input{Width: msg.Width, Height: msg.Height})
+
+	case tea.KeyPressMsg:
+		if msg.String() == "ctrl+c" {
+			return m, tea.Quit
+		}
+		// viewers must not stop the instance
+		if key.Matches(msg, m.quit...) {
+			return m, nil
+		}
+		k := tea.Key(msg)
+		m.attachment.Send(remote.Input{Key: &k})
+
+	case tea.PasteMsg:
+		m.attachment.Send(remote.Input{Paste: string(msg)})
+	}
+
+	return m, nil
+}
+
+// View shows the view of the instance, cropped to the terminal since the
+// instance may be laid out for a bigger one.
+func (m attachModel) View() string {
+	if m.view == "" {
+		return "Attaching..."
+	}
+
+	lines := strings.Split(m.view, "\n")
+	if m.height > 0 && len(lines) > m.height {
+		lines = lines[:m.height]
+	}
+	if m.width > 0 {
+		for i, line := range lines {
+			lines[i] = ansi.Truncate(line, m.width, "")
+		}
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+func waitForFrame(attachment *remote.Attachment) tea.Cmd {
+	return func() tea.Msg {
+		view, ok := <-attachment.Frames()
+		if !ok {
+			return detachedMsg{}
+		}
+		return frameMsg(view)
+	}
+}
@@ -0,0 +1,47 @@
+package program
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"mark/internal/app"
+	"mark/internal/config"
+	"mark/internal/llm/provider"
+	"mark/internal/llm/providers"
+	"mark/internal/markdown"
+	"mark/internal/remote"
+)
+
+// DefaultConfig returns the configuration used when nothing is set, see
+// config.Options.
+func DefaultConfig() config.Config {
+	return config.Config{
+		Provider:       "openai",
+		Model:          "gpt-4o",
+		Theme:          "auto",
+		FrameRate:      app.DefaultFrameRate,
+		MaxRetries:     provider.DefaultRetryPolicy().MaxRetries,
+		MaxRequestSize: remote.DefaultMaxRequestSize,
+	}
+}
+
+// ValidateConfig checks the settings of c the config package doesn't know
+// about: the provider, the theme and the key bindings.
+func ValidateConfig(c config.Config, sources config.Sources) []error {
+	var errs []error
+
+	if names := providers.Names(); !slices.Contains(names, c.Provider) {
+		errs = append(errs, sources.Invalid("provider", fmt.Errorf("unknown provider %q, expected one of %s", c.Provider, strings.Join(names, ", "))))
+	}
+
+	if styles := markdown.Styles(); !slices.Contains(styles, c.Theme) {
+		errs = append(errs, sources.Invalid("theme", fmt.Errorf("unknown theme %q, expected one of %s", c.Theme, strings.Join(styles, ", "))))
+	}
+
+	if _, err := app.NewKeyMap(c.Keys); err != nil {
+		errs = append(errs, err)
+	}
+
+	return errs
+}
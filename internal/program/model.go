@@ -0,0 +1,58 @@
+package program
+
+import (
+	"mark/internal/remote"
+
+	tea "github.com/charmbracelet/bubbletea/v2"
+)
+
+// publisher publishes the view of a model to the clients attached to the
+// server after every update.
+type publisher struct {
+	model  tea.Model
+	server *remote.Server
+}
+
+func (p publisher) Init() tea.Cmd {
+	return p.model.Init()
+}
+
+func (p publisher) update(msg tea.Msg) (publisher, tea.Cmd) {
+	model, cmd := p.model.Update(msg)
+	p.model = model
+
+	if p.server.Attached() {
+		if viewModel, ok := p.model.(tea.ViewModel); ok {
+			p.server.PublishView(viewModel.View())
+		}
+	}
+
+	return p, cmd
+}
+
+// headlessModel runs a model without rendering it locally. Bubbletea
+// doesn't create a renderer for models without a View method.
+type headlessModel struct {
+	publisher
+}
+
+func (m headlessModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	var cmd tea.Cmd
+	m.publisher, cmd = m.publisher.update(msg)
+	return m, cmd
+}
+
+// terminalModel runs a model rendering it in the terminal.
+type terminalModel struct {
+	publisher
+}
+
+func (m terminalModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	var cmd tea.Cmd
+	m.publisher, cmd = m.publisher.update(msg)
+	return m, cmd
+}
+
+func (m terminalModel) View() string {
+	return m.model.(tea.ViewModel).View()
+}
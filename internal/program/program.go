@@ -1,10 +1,15 @@
 package program
 
 import (
+	"errors"
 	"fmt"
+	"io"
 	"os"
 
 	"mark/internal/app"
+	"mark/internal/config"
+	"mark/internal/llm/providers"
+	"mark/internal/persona"
 	"mark/internal/remote"
 
 	tea "github.com/charmbracelet/bubbletea/v2"
@@ -14,17 +19,50 @@ import (
 type Program struct {
 	TeaProgram *tea.Program
 	server     *remote.Server
-	events     chan tea.Msg
 }
 
+// Options configures a Program.
+type Options struct {
+	// Token, when not empty, must be presented by every request sent to the
+	// control socket.
+	Token string
+
+	// Headless runs the App without a terminal UI. The session is driven
+	// only through the control socket.
+	Headless bool
+
+	// Config is the configuration of the program, see config.Load.
+	Config config.Config
+}
+
+// headlessSize is the size the App is laid out with when running headless.
+var headlessSize = tea.WindowSizeMsg{Width: 120, Height: 40}
+
 // / NewProgram creates a new Program.
-func NewProgram() (*Program, error) {
+func NewProgram(options Options) (*Program, error) {
 	// determine the current working directory
 	cwd, err := os.Getwd()
 	if err != nil {
 		return nil, err
 	}
 
+	cfg := options.Config
+
+	root, err := remote.ProjectRoot(cwd)
+	if err != nil {
+		return nil, fmt.Errorf("failed to determine project root: %w", err)
+	}
+
+	keys, err := app.NewKeyMap(cfg.Keys)
+	if err != nil {
+		return nil, err
+	}
+
+	llm, err := providers.New(cfg.Provider, cfg.Model, cfg.BaseURL)
+	if err != nil {
+		return nil, err
+	}
+
 	// create an events channel
 	events := make(chan tea.Msg)
 
@@ -33,38 +71,82 @@ func NewProgram() (*Program, error) {
 	if err != nil {
 		return nil, fmt.Errorf("failed to create server: %w", err)
 	}
+	server.SetToken(options.Token)
+	server.SetMaxRequestSize(cfg.MaxRequestSize)
 
 	// initialize the App model
 	m, err := app.MakeApp(cwd, events)
 	if err != nil {
+		server.Close()
+		return nil, err
+	}
+	m.SetKeyMap(keys)
+	m.SetProvider(llm)
+	m.SetTheme(cfg.Theme)
+	m.SetFrameRate(cfg.FrameRate)
+	m.SetMaxRetries(cfg.MaxRetries)
+	m.SetSocketPath(server.SocketPath())
+
+	// personas of the user and of the project
+	dirs, err := config.PersonaDirs(root)
+	if err != nil {
+		server.Close()
+		return nil, err
+	}
+	personas, err := persona.Load(dirs...)
+	if err != nil {
+		server.Close()
+		return nil, err
+	}
+	if err := m.SetPersonas(personas, cfg.SystemPrompt, cfg.Persona); err != nil {
+		server.Close()
 		return nil, err
 	}
 
 	// create the bubbletea program
-	teaprogram := tea.NewProgram(m, tea.WithAltScreen(), tea.WithKeyboardEnhancements())
+	var teaprogram *tea.Program
+	if options.Headless {
+		// take the size of attached clients since there's no terminal
+		server.SetFollowClientSize(true)
+
+		teaprogram = tea.NewProgram(
+			headlessModel{publisher{m, server}},
+			tea.WithInput(nil),
+			tea.WithOutput(io.Discard),
+			tea.WithWindowSize(headlessSize.Width, headlessSize.Height),
+		)
+	} else {
+		teaprogram = tea.NewProgram(terminalModel{publisher{m, server}}, tea.WithAltScreen(), tea.WithKeyboardEnhancements())
+	}
 
 	// create the program
 	program := &Program{
 		TeaProgram: teaprogram,
 		server:     server,
-		events:     events,
 	}
 
 	return program, nil
 }
 
+// SocketPath returns the path of the control socket.
+func (p *Program) SocketPath() string {
+	return p.server.SocketPath()
+}
+
 // Run runs the bubbletea program.
+// The server is always closed when the program exits, so the socket doesn't
+// outlive the process. The events channel is left open: connection handlers
+// and the agent may still be sending on it, and they stop with the process.
 func (p *Program) Run() error {
+	defer p.server.Close()
+
 	go p.server.Run()
 
 	// run the tea program
 	_, err := p.TeaProgram.Run()
-	if err != nil {
+	if err != nil && !errors.Is(err, tea.ErrInterrupted) {
 		return err
 	}
 
-	p.server.Close()
-	close(p.events)
-
 	return nil
 }
@@ -0,0 +1,98 @@
+package remote
+
+import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"os"
+	"syscall"
+)
+
+// Access to the control socket is restricted in layers:
+//   - the socket lives in a directory only accessible by the user
+//   - the socket file itself is only accessible by the user
+//   - the uid of every connecting process must match the uid of the server
+//   - optionally, every request must carry the token of the instance
+
+var errPeerCredUnsupported = errors.New("peer credentials are not supported on this platform")
+
+// ErrPermissionDenied is reported to clients that are not allowed to use
+// the socket.
+var ErrPermissionDenied = errors.New("permission denied")
+
+// ErrInvalidToken is reported to clients that don't present the token the
+// server was configured with.
+var ErrInvalidToken = errors.New("invalid or missing token")
+
+// ensurePrivateDir creates dir if needed and makes sure only the current
+// user can access it. dir must belong to mark, like the directory returned
+// by RuntimeDir, since its permissions are changed.
+func ensurePrivateDir(dir string) error {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return fmt.Errorf("failed to create directory for socket file: %w", err)
+	}
+
+	info, err := os.Stat(dir)
+	if err != nil {
+		return fmt.Errorf("failed to check directory for socket file: %w", err)
+	}
+
+	if stat, ok := info.Sys().(*syscall.Stat_t); ok && int(stat.Uid) != os.Getuid() {
+		return fmt.Errorf("directory for socket file %s is owned by another user", dir)
+	}
+
+	if info.Mode().Perm()&0o077 != 0 {
+		if err := os.Chmod(dir, 0o700); err != nil {
+			return fmt.Errorf("failed to restrict permissions of %s: %w", dir, err)
+		}
+	}
+
+	return nil
+}
+
+// restrictSocket makes the socket file only accessible by the current user.
+func restrictSocket(socketPath string) error {
+	if err := os.Chmod(socketPath, fs.FileMode(0o600)); err != nil {
+		return fmt.Errorf("failed to restrict permissions of socket: %w", err)
+	}
+	return nil
+}
+
+// authorizePeer checks that the process on the other end of conn belongs to
+// the same user as the server.
+func authorizePeer(conn net.Conn) error {
+	unixConn, ok := conn.(*net.UnixConn)
+	if !ok {
+		return ErrPermissionDenied
+	}
+
+	uid, err := peerUID(unixConn)
+	if errors.Is(err, errPeerCredUnsupported) {
+		return nil // rely on the permissions of the socket
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get peer credentials: %w", err)
+	}
+
+	if uid != os.Getuid() {
+		return ErrPermissionDenied
+	}
+
+	return nil
+}
+
+// authorizeToken checks the token presented in a request. All requests are
+// accepted when the server has no token.
+func authorizeToken(expected string, actual string) error {
+	if expected == "" {
+		return nil
+	}
+
+	if subtle.ConstantTimeCompare([]byte(expected), []byte(actual)) != 1 {
+		return ErrInvalidToken
+	}
+
+	return nil
+}
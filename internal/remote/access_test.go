@@ -0,0 +1,120 @@
+package remote
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAccess(t *testing.T) {
+	t.Parallel()
+
+	t.Run("socket is private", func(t *testing.T) {
+		t.Parallel()
+
+		root, _ := makeProject(t)
+
+		server, err := NewServer(root, make(chan tea.Msg))
+		require.NoError(t, err)
+		defer server.Close()
+
+		info, err := os.Stat(server.SocketPath())
+		require.NoError(t, err)
+		assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+
+		info, err = os.Stat(filepath.Dir(server.SocketPath()))
+		require.NoError(t, err)
+		assert.Equal(t, os.FileMode(0o700), info.Mode().Perm())
+	})
+
+	t.Run("peer uid", func(t *testing.T) {
+		t.Parallel()
+
+		root, _ := makeProject(t)
+
+		server, err := NewServer(root, make(chan tea.Msg))
+		require.NoError(t, err)
+		defer server.Close()
+
+		conn, err := net.Dial("unix", server.SocketPath())
+		require.NoError(t, err)
+		defer conn.Close()
+
+		serverConn, err := server.listener.Accept()
+		require.NoError(t, err)
+		defer serverConn.Close()
+
+		uid, err := peerUID(serverConn.(*net.UnixConn))
+		if err == errPeerCredUnsupported {
+			t.Skip(err)
+		}
+		require.NoError(t, err)
+		assert.Equal(t, os.Getuid(), uid)
+		assert.NoError(t, authorizePeer(serverConn))
+	})
+
+	t.Run("token", func(t *testing.T) {
+		t.Parallel()
+
+		root, _ := makeProject(t)
+
+		events := make(chan tea.Msg, 1)
+		server, err := NewServer(root, events)
+		require.NoError(t, err)
+		server.SetToken("secret")
+		go server.Run()
+		defer server.Close()
+
+		received := fakeApp(t, events, nil)
+
+		client, err := NewClient(root)
+		require.NoError(t, err)
+
+		err = client.SendRequest(Request{Command: "run"})
+		require.Error(t, err)
+		assert.Equal(t, ErrInvalidToken.Error(), err.Error())
+
+		err = client.SendRequest(Request{Command: "run", Token: "wrong"})
+		require.Error(t, err)
+		assert.Equal(t, ErrInvalidToken.Error(), err.Error())
+
+		assert.Empty(t, received)
+
+		err = client.SendRequest(Request{Command: "run", Token: "secret"})
+		require.NoError(t, err)
+		assert.Len(t, received, 1)
+	})
+}
+
+// TestSharedRuntimeDir isn't parallel since it changes the runtime directory
+// of every test.
+func TestSharedRuntimeDir(t *testing.T) {
+	shared := t.TempDir()
+	require.NoError(t, os.Chmod(shared, 0o755))
+
+	SetRuntimeDir(shared)
+	t.Cleanup(func() { SetRuntimeDir("") })
+
+	root, _ := makeProject(t)
+
+	server, err := NewServer(root, make(chan tea.Msg))
+	require.NoError(t, err)
+	defer server.Close()
+
+	// the sockets go in a private directory, the shared one is left alone
+	dir := filepath.Dir(server.SocketPath())
+	assert.Equal(t, shared, filepath.Dir(dir))
+
+	info, err := os.Stat(dir)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o700), info.Mode().Perm())
+
+	info, err = os.Stat(shared)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o755), info.Mode().Perm())
+}
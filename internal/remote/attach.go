@@ -0,0 +1,267 @@
+package remote
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+
+	tea "github.com/charmbracelet/bubbletea/v2"
+)
+
+// Attaching lets a TUI in another terminal show and control a running
+// instance. The attached client sends an "attach" request, after which the
+// connection carries Frame messages from the server, with the rendered view
+// of the instance, and Input messages from the client, with the keys pressed
+// and the size of its terminal.
+
+// AttachCommand is the request command starting an attached session.
+const AttachCommand = "attach"
+
+// Frame is the rendered view of the instance.
+type Frame struct {
+	View string `json:"view"`
+}
+
+// Input is an event in the terminal of an attached client.
+type Input struct {
+	Key    *tea.Key `json:"key,omitempty"`
+	Paste  string   `json:"paste,omitempty"`
+	Width  int      `json:"width,omitempty"`
+	Height int      `json:"height,omitempty"`
+}
+
+// ClientAttachedMsg is sent to the App when a client attaches, so a fresh
+// view is published for it.
+type ClientAttachedMsg struct{}
+
+// views keeps the latest view published by the instance and delivers it to
+// attached clients. Slow clients skip intermediate views.
+type views struct {
+	mu          sync.Mutex
+	latest      string
+	subscribers map[chan string]struct{}
+}
+
+func (v *views) subscribe() chan string {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if v.subscribers == nil {
+		v.subscribers = map[chan string]struct{}{}
+	}
+
+	ch := make(chan string, 1)
+	if v.latest != "" {
+		ch <- v.latest
+	}
+	v.subscribers[ch] = struct{}{}
+
+	return ch
+}
+
+func (v *views) unsubscribe(ch chan string) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	delete(v.subscribers, ch)
+}
+
+func (v *views) publish(view string) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if view == v.latest {
+		return
+	}
+	v.latest = view
+
+	for ch := range v.subscribers {
+		// replace a view the client didn't get to yet
+		select {
+		case <-ch:
+		default:
+		}
+		ch <- view
+	}
+}
+
+func (v *views) hasSubscribers() bool {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	return len(v.subscribers) > 0
+}
+
+// Attached checks if any client is attached. Views only need to be
+// published while this is true.
+func (s *Server) Attached() bool {
+	return s.views.hasSubscribers()
+}
+
+// PublishView sends the rendered view of the instance to attached clients.
+func (s *Server) PublishView(view string) {
+	s.views.publish(view)
+}
+
+// SetFollowClientSize makes the instance take the terminal size of attached
+// clients. Used when the instance has no terminal of its own.
+func (s *Server) SetFollowClientSize(follow bool) {
+	s.followClientSize = follow
+}
+
+// serveAttach runs an attached session on conn until the client detaches.
+func (s *Server) serveAttach(conn net.Conn) {
+	frames := s.views.subscribe()
+	defer s.views.unsubscribe(frames)
+
+	// render a view for the new client
+	s.events <- ClientAttachedMsg{}
+
+	done := make(chan struct{})
+	defer close(done)
+
+	go func() {
+		for {
+			select {
+			case view := <-frames:
+				if err := writeFrame(conn, &Frame{View: view}); err != nil {
+					conn.Close()
+					return
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	for {
+		input := Input{}
+		if err := readFrame(conn, s.maxRequestSize, &input); err != nil {
+			return // client detached
+		}
+
+		if msg := s.inputToTeaMsg(input); msg != nil {
+			s.events <- msg
+		}
+	}
+}
+
+func (s *Server) inputToTeaMsg(input Input) tea.Msg {
+	switch {
+	case input.Key != nil:
+		return tea.KeyPressMsg(*input.Key)
+	case input.Paste != "":
+		return tea.PasteMsg(input.Paste)
+	case input.Width > 0 && input.Height > 0 && s.followClientSize:
+		return tea.WindowSizeMsg{Width: input.Width, Height: input.Height}
+	}
+
+	return nil
+}
+
+// Attachment is the client side of an attached session.
+type Attachment struct {
+	conn      net.Conn
+	frames    chan string
+	closed    chan struct{} // closed by Close
+	closeOnce sync.Once
+	done      chan struct{} // closed when readFrames returns
+	err       error         // set by readFrames before done is closed
+}
+
+// Attach starts an attached session with the instance.
+func (client *Client) Attach(token string) (*Attachment, error) {
+	if client.socketPath == "" {
+		return nil, fmt.Errorf("Couldn't find a running mark instance for %s", client.cwd)
+	}
+
+	conn, hello, err := client.connect()
+	if err != nil {
+		return nil, err
+	}
+
+	if !hello.Supports(AttachCommand) {
+		conn.Close()
+		return nil, &UnsupportedCommandError{Command: AttachCommand}
+	}
+
+	err = writeFrame(conn, &Request{Command: AttachCommand, Token: token})
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+
+	attachment := &Attachment{
+		conn:   conn,
+		frames: make(chan string),
+		closed: make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+
+	go attachment.readFrames()
+
+	return attachment, nil
+}
+
+// Frames returns the views published by the instance. The channel is closed
+// when the session ends, see Err for the reason.
+func (a *Attachment) Frames() <-chan string {
+	return a.frames
+}
+
+// Err returns the error that ended the session, if any. It waits for the
+// session to end, call Close first to detach.
+func (a *Attachment) Err() error {
+	<-a.done
+	return a.err
+}
+
+// Send sends an input event to the instance.
+func (a *Attachment) Send(input Input) error {
+	return writeFrame(a.conn, &input)
+}
+
+// Close detaches from the instance, waiting for the frames to stop.
+func (a *Attachment) Close() error {
+	var err error
+	a.closeOnce.Do(func() {
+		close(a.closed)
+		err = a.conn.Close()
+	})
+	<-a.done
+	return err
+}
+
+func (a *Attachment) readFrames() {
+	defer close(a.done)
+	defer close(a.frames)
+
+	for {
+		// responses and frames share the connection: a response means the
+		// attach request was refused
+		frame := struct {
+			Frame
+			Response
+		}{}
+		err := readFrame(a.conn, DefaultMaxRequestSize, &frame)
+		if err != nil {
+			if !errors.Is(err, io.EOF) && !errors.Is(err, net.ErrClosed) {
+				a.err = err
+			}
+			return
+		}
+
+		if frame.Error != "" {
+			a.err = errors.New(frame.Error)
+			return
+		}
+
+		select {
+		case a.frames <- frame.View:
+		case <-a.closed:
+			return // nobody receives the frames anymore
+		}
+	}
+}
@@ -0,0 +1,147 @@
+package remote
+
+import (
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAttach(t *testing.T) {
+	t.Parallel()
+
+	t.Run("mirrors views and forwards input", func(t *testing.T) {
+		t.Parallel()
+
+		root, _ := makeProject(t)
+
+		events := make(chan tea.Msg, 10)
+		server, err := NewServer(root, events)
+		require.NoError(t, err)
+		server.SetFollowClientSize(true)
+		go server.Run()
+		defer server.Close()
+
+		assert.False(t, server.Attached())
+
+		client, err := NewClient(root)
+		require.NoError(t, err)
+
+		attachment, err := client.Attach("")
+		require.NoError(t, err)
+		defer attachment.Close()
+
+		// the App is asked to render a view for the new client
+		assert.Equal(t, ClientAttachedMsg{}, receive(t, events))
+		assert.True(t, server.Attached())
+
+		server.PublishView("first view")
+		assert.Equal(t, "first view", receive(t, attachment.Frames()))
+
+		server.PublishView("second view")
+		assert.Equal(t, "second view", receive(t, attachment.Frames()))
+
+		require.NoError(t, attachment.Send(Input{Key: &tea.Key{Code: 'n', Text: "n"}}))
+		assert.Equal(t, tea.KeyPressMsg{Code: 'n', Text: "n"}, receive(t, events))
+
+		require.NoError(t, attachment.Send(Input{Width: 100, Height: 30}))
+		assert.Equal(t, tea.WindowSizeMsg{Width: 100, Height: 30}, receive(t, events))
+
+		attachment.Close()
+		assert.Eventually(t, func() bool { return !server.Attached() }, time.Second, 10*time.Millisecond)
+	})
+
+	t.Run("new clients get the latest view", func(t *testing.T) {
+		t.Parallel()
+
+		root, _ := makeProject(t)
+
+		events := make(chan tea.Msg, 10)
+		server, err := NewServer(root, events)
+		require.NoError(t, err)
+		go server.Run()
+		defer server.Close()
+
+		server.PublishView("current view")
+
+		client, err := NewClient(root)
+		require.NoError(t, err)
+
+		attachment, err := client.Attach("")
+		require.NoError(t, err)
+		defer attachment.Close()
+
+		assert.Equal(t, "current view", receive(t, attachment.Frames()))
+
+		// the size of clients is ignored by instances with their own terminal
+		require.NoError(t, attachment.Send(Input{Width: 100, Height: 30}))
+		require.NoError(t, attachment.Send(Input{Paste: "pasted"}))
+		assert.Equal(t, ClientAttachedMsg{}, receive(t, events))
+		assert.Equal(t, tea.PasteMsg("pasted"), receive(t, events))
+	})
+
+	t.Run("Close stops a client not reading frames", func(t *testing.T) {
+		t.Parallel()
+
+		root, _ := makeProject(t)
+
+		events := make(chan tea.Msg, 10)
+		server, err := NewServer(root, events)
+		require.NoError(t, err)
+		go server.Run()
+		defer server.Close()
+
+		client, err := NewClient(root)
+		require.NoError(t, err)
+
+		attachment, err := client.Attach("")
+		require.NoError(t, err)
+
+		// the frame is sent but never received
+		assert.Equal(t, ClientAttachedMsg{}, receive(t, events))
+		server.PublishView("unread view")
+
+		closed := make(chan error)
+		go func() { closed <- attachment.Close() }()
+		assert.NoError(t, receive(t, closed))
+		assert.NoError(t, attachment.Err())
+	})
+
+	t.Run("refused without the token", func(t *testing.T) {
+		t.Parallel()
+
+		root, _ := makeProject(t)
+
+		server, err := NewServer(root, make(chan tea.Msg, 10))
+		require.NoError(t, err)
+		server.SetToken("secret")
+		go server.Run()
+		defer server.Close()
+
+		client, err := NewClient(root)
+		require.NoError(t, err)
+
+		attachment, err := client.Attach("wrong")
+		require.NoError(t, err)
+		defer attachment.Close()
+
+		_, ok := <-attachment.Frames()
+		assert.False(t, ok)
+		assert.EqualError(t, attachment.Err(), ErrInvalidToken.Error())
+	})
+}
+
+func receive[T any](t *testing.T, ch <-chan T) T {
+	t.Helper()
+
+	select {
+	case v := <-ch:
+		return v
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for a value")
+		var zero T
+		return zero
+	}
+}
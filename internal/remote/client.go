@@ -1,16 +1,15 @@
 package remote
 
 import (
-	"encoding/json"
 	"errors"
 	"fmt"
 	"io/fs"
 	"net"
 	"os"
-	"path"
 )
 
 type Client struct {
+	cwd        string
 	socketPath string
 }
 
@@ -18,42 +17,128 @@ type Request struct {
 	Command string   `json:"command"`
 	Args    []string `json:"args,omitempty"`
 	Stdin   string   `json:"stdin,omitempty"`
+	Token   string   `json:"token,omitempty"`
 }
 
+// NewClient creates a client for the instance running in the project
+// containing cwd. See FindSocket for how the instance is found.
 func NewClient(cwd string) (*Client, error) {
+	socketPath, err := FindSocket(cwd)
+	if err != nil {
+		return nil, err
+	}
+
 	client := Client{
-		socketPath: path.Join(cwd, ".local", "share", "mark", "socket"),
+		cwd:        cwd,
+		socketPath: socketPath,
 	}
 	return &client, nil
 }
 
+// SocketPath returns the path of the socket of the instance the client
+// connects to, or an empty string if no instance was found.
+func (client *Client) SocketPath() string {
+	return client.socketPath
+}
+
 func (client *Client) SendRequest(req Request) error {
-	// Check if the socket exists
+	_, err := client.Query(req)
+	return err
+}
+
+// Query sends a request and returns the output produced by the instance.
+// Only some commands produce output, like the reply of the session.
+func (client *Client) Query(req Request) (string, error) {
+	// Check if an instance was found
+	if client.socketPath == "" {
+		return "", fmt.Errorf("Couldn't find a running mark instance for %s", client.cwd)
+	}
+
+	// Check if the socket still exists
 	_, err := os.Stat(client.socketPath)
 	if errors.Is(err, fs.ErrNotExist) {
-		return fmt.Errorf("Couldn't find socket path: %s", client.socketPath)
+		return "", fmt.Errorf("Couldn't find socket path: %s", client.socketPath)
 	}
 
 	// open the socket connection
-	conn, err := client.openSocketConnection(client.socketPath)
+	conn, hello, err := client.connect()
 	if err != nil {
-		return err
+		return "", err
 	}
 	defer conn.Close()
 
-	// Convert the message to JSON format
-	data, err := json.Marshal(&req)
+	// Fail clearly instead of sending commands the instance doesn't know
+	if !hello.Supports(req.Command) {
+		return "", &UnsupportedCommandError{Command: req.Command}
+	}
+
+	// Write the request to the socket
+	err = writeFrame(conn, &req)
+	if err != nil {
+		return "", fmt.Errorf("failed to send request: %w", err)
+	}
+
+	// Wait for the server to process the request
+	res := Response{}
+	err = readFrame(conn, DefaultMaxRequestSize, &res)
+	if err != nil {
+		return "", fmt.Errorf("failed to read response: %w", err)
+	}
+
+	if res.Error != "" {
+		return "", errors.New(res.Error)
+	}
+
+	return res.Output, nil
+}
+
+// Hello connects to the instance and returns its handshake, which describes
+// the commands it supports.
+func (client *Client) Hello() (Hello, error) {
+	if client.socketPath == "" {
+		return Hello{}, fmt.Errorf("Couldn't find a running mark instance for %s", client.cwd)
+	}
+
+	conn, hello, err := client.connect()
 	if err != nil {
-		return fmt.Errorf("failed to marshal message: %w", err)
+		return Hello{}, err
 	}
+	conn.Close()
+
+	return hello, nil
+}
 
-	// Write the message to the socket
-	_, err = conn.Write(data)
+// connect opens a connection to the instance and performs the handshake.
+func (client *Client) connect() (net.Conn, Hello, error) {
+	conn, err := client.openSocketConnection(client.socketPath)
 	if err != nil {
-		return err
+		return nil, Hello{}, err
+	}
+
+	err = writeFrame(conn, &Hello{ProtocolVersion: ProtocolVersion})
+	if err != nil {
+		conn.Close()
+		return nil, Hello{}, fmt.Errorf("failed to send handshake: %w", err)
+	}
+
+	hello := Hello{}
+	err = readFrame(conn, DefaultMaxRequestSize, &hello)
+	if err != nil {
+		conn.Close()
+		return nil, Hello{}, fmt.Errorf("failed to read handshake: %w", err)
+	}
+
+	if hello.Error != "" {
+		conn.Close()
+		return nil, Hello{}, errors.New(hello.Error)
+	}
+
+	if hello.ProtocolVersion != ProtocolVersion {
+		conn.Close()
+		return nil, Hello{}, &VersionMismatchError{Client: ProtocolVersion, Server: hello.ProtocolVersion}
 	}
 
-	return nil
+	return conn, hello, nil
 }
 
 func (self *Client) openSocketConnection(socketPath string) (net.Conn, error) {
@@ -1,8 +1,10 @@
 package remote
 
 import (
+	"errors"
 	"log/slog"
 	"os"
+	"strings"
 	"testing"
 
 	"mark/internal/app"
@@ -18,11 +20,47 @@ func TestMain(m *testing.M) {
 	// sockets have a limit of 104 characters.
 	os.Setenv(("TMPDIR"), "/tmp/")
 
+	// keep sockets of test servers away from the user's running instances.
+	runtimeDir, err := os.MkdirTemp("", "mark-test-runtime")
+	if err != nil {
+		panic(err)
+	}
+	os.Setenv("XDG_RUNTIME_DIR", runtimeDir)
+
 	v := m.Run()
 
+	os.RemoveAll(runtimeDir)
+
 	os.Exit(v)
 }
 
+// fakeApp handles the messages sent to events in place of the App,
+// answering commands with err. Returns the messages of the commands.
+func fakeApp(t *testing.T, events chan tea.Msg, err error) <-chan tea.Msg {
+	received := make(chan tea.Msg, cap(events))
+	stop := make(chan struct{})
+	t.Cleanup(func() { close(stop) })
+
+	go func() {
+		for {
+			select {
+			case msg := <-events:
+				command, ok := msg.(app.CommandMsg)
+				if !ok {
+					received <- msg
+					continue
+				}
+				received <- command.Msg
+				command.Done(err)
+			case <-stop:
+				return
+			}
+		}
+	}()
+
+	return received
+}
+
 func TestClient(t *testing.T) {
 	t.Parallel()
 
@@ -40,28 +78,126 @@ func TestClient(t *testing.T) {
 
 			err = client.SendRequest(Request{Command: "test-message", Args: []string{"arg1", "arg2"}})
 			require.Error(t, err)
-			assert.Equal(t, "Couldn't find socket path: testdata/nonexistent/.local/share/mark/socket", err.Error())
+			assert.Equal(t, "Couldn't find a running mark instance for testdata/nonexistent", err.Error())
 		})
 
 		t.Run("socket path exists", func(t *testing.T) {
 			t.Parallel()
 
-			events := make(chan tea.Msg)
+			events := make(chan tea.Msg, 1)
 			cwd := t.TempDir()
 			server, err := NewServer(cwd, events)
 			require.NoError(t, err)
 			go server.Run()
 			defer server.Close()
 
+			received := fakeApp(t, events, nil)
+
 			client, err := NewClient(cwd)
 			require.NoError(t, err)
 
 			err = client.SendRequest(Request{Command: "add-context-item-text", Args: []string{"prompt"}, Stdin: "stdin content"})
 			require.NoError(t, err)
 
-			msg := <-events
+			msg := <-received
 			slog.Info("Received message", "msg", msg)
 			assert.Equal(t, app.AddContextItemTextMsg("prompt\nstdin content"), msg)
 		})
+
+		t.Run("large stdin", func(t *testing.T) {
+			t.Parallel()
+
+			events := make(chan tea.Msg, 1)
+			cwd := t.TempDir()
+			server, err := NewServer(cwd, events)
+			require.NoError(t, err)
+			go server.Run()
+			defer server.Close()
+
+			received := fakeApp(t, events, nil)
+
+			client, err := NewClient(cwd)
+			require.NoError(t, err)
+
+			stdin := strings.Repeat("+ a line from a very large diff\n", 256*1024) // 8MB
+			err = client.SendRequest(Request{Command: "add-context-item-text", Args: []string{"prompt"}, Stdin: stdin})
+			require.NoError(t, err)
+
+			msg := <-received
+			assert.Equal(t, app.AddContextItemTextMsg("prompt\n"+stdin), msg)
+		})
+
+		t.Run("multiple requests", func(t *testing.T) {
+			t.Parallel()
+
+			events := make(chan tea.Msg, 2)
+			cwd := t.TempDir()
+			server, err := NewServer(cwd, events)
+			require.NoError(t, err)
+			go server.Run()
+			defer server.Close()
+
+			received := fakeApp(t, events, nil)
+
+			client, err := NewClient(cwd)
+			require.NoError(t, err)
+
+			err = client.SendRequest(Request{Command: "add-context-item-file", Args: []string{"file.txt"}})
+			require.NoError(t, err)
+			err = client.SendRequest(Request{Command: "run"})
+			require.NoError(t, err)
+
+			assert.Equal(t, app.AddContextItemFileMsg("file.txt"), <-received)
+			assert.Equal(t, app.RunMsg{}, <-received)
+		})
+
+		t.Run("request larger than the maximum size", func(t *testing.T) {
+			t.Parallel()
+
+			events := make(chan tea.Msg, 1)
+			cwd := t.TempDir()
+			server, err := NewServer(cwd, events)
+			require.NoError(t, err)
+			server.SetMaxRequestSize(1024)
+			go server.Run()
+			defer server.Close()
+
+			received := fakeApp(t, events, nil)
+
+			client, err := NewClient(cwd)
+			require.NoError(t, err)
+
+			stdin := strings.Repeat("x", 4096)
+			err = client.SendRequest(Request{Command: "add-context-item-text", Args: []string{"prompt"}, Stdin: stdin})
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), "request too large")
+			assert.Contains(t, err.Error(), "maximum is 1024 bytes")
+
+			// the server keeps accepting smaller requests
+			err = client.SendRequest(Request{Command: "run"})
+			require.NoError(t, err)
+			assert.Equal(t, app.RunMsg{}, <-received)
+			assert.Empty(t, received)
+		})
+
+		t.Run("failed command", func(t *testing.T) {
+			t.Parallel()
+
+			events := make(chan tea.Msg, 1)
+			cwd := t.TempDir()
+			server, err := NewServer(cwd, events)
+			require.NoError(t, err)
+			go server.Run()
+			defer server.Close()
+
+			received := fakeApp(t, events, errors.New("no such file"))
+
+			client, err := NewClient(cwd)
+			require.NoError(t, err)
+
+			err = client.SendRequest(Request{Command: "add-context-item-file", Args: []string{"missing.txt"}})
+			require.EqualError(t, err, "no such file")
+			assert.Equal(t, app.AddContextItemFileMsg("missing.txt"), <-received)
+		})
 	})
 }
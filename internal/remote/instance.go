@@ -0,0 +1,223 @@
+package remote
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Instance describes a running mark TUI.
+type Instance struct {
+	Root      string    `json:"root"`
+	Socket    string    `json:"socket"`
+	PID       int       `json:"pid"`
+	StartedAt time.Time `json:"started_at"`
+}
+
+// runtimeDir replaces the runtime directory when not empty.
+var runtimeDir string
+
+// SetRuntimeDir makes a private directory inside dir the directory of the
+// sockets of running instances, for the server and the clients alike. dir
+// may be shared with other users, its permissions are left alone. Empty
+// restores the default.
+func SetRuntimeDir(dir string) {
+	runtimeDir = dir
+}
+
+// RuntimeDir returns the directory where sockets of running instances are
+// created. It is a per user directory inside the directory given to
+// SetRuntimeDir, else $XDG_RUNTIME_DIR/mark when set, otherwise a per user
+// directory inside the system temporary directory.
+func RuntimeDir() string {
+	if runtimeDir != "" {
+		return filepath.Join(runtimeDir, userDirName())
+	}
+
+	if dir := os.Getenv("XDG_RUNTIME_DIR"); dir != "" {
+		return filepath.Join(dir, "mark")
+	}
+
+	return filepath.Join(os.TempDir(), userDirName())
+}
+
+// userDirName is the name of the directory of the current user inside a
+// directory shared with other users.
+func userDirName() string {
+	return "mark-" + strconv.Itoa(os.Getuid())
+}
+
+// ProjectRoot returns the root of the git repository containing dir, or dir
+// itself when it is not inside a git repository.
+func ProjectRoot(dir string) (string, error) {
+	dir, err := canonicalPath(dir)
+	if err != nil {
+		return "", err
+	}
+
+	if root, ok := gitRoot(dir); ok {
+		return root, nil
+	}
+	return dir, nil
+}
+
+// gitRoot returns the root of the git repository containing dir, false when
+// dir is not inside a git repository.
+func gitRoot(dir string) (string, bool) {
+	for current := dir; ; current = filepath.Dir(current) {
+		if _, err := os.Stat(filepath.Join(current, ".git")); err == nil {
+			return current, true
+		}
+
+		if filepath.Dir(current) == current {
+			return "", false
+		}
+	}
+}
+
+// SocketPath returns the socket path of the instance for a project root.
+func SocketPath(root string) string {
+	return filepath.Join(RuntimeDir(), instanceKey(root)+".sock")
+}
+
+// FindSocket looks for the socket of a running instance for dir. Inside a git
+// repository it is the instance of the root of the repository, otherwise the
+// instance of dir or of the closest of its parents. Returns an empty string
+// when no instance is found.
+func FindSocket(dir string) (string, error) {
+	dir, err := canonicalPath(dir)
+	if err != nil {
+		return "", err
+	}
+
+	// instances in a git repository run for its root
+	if root, ok := gitRoot(dir); ok {
+		if socketPath := SocketPath(root); exists(socketPath) {
+			return socketPath, nil
+		}
+		return "", nil
+	}
+
+	for current := dir; ; current = filepath.Dir(current) {
+		if socketPath := SocketPath(current); exists(socketPath) {
+			return socketPath, nil
+		}
+
+		if filepath.Dir(current) == current {
+			return "", nil
+		}
+	}
+}
+
+// exists checks if there is a file at path.
+func exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// ListInstances returns the instances that are currently accepting
+// connections, sorted by project root.
+func ListInstances() ([]Instance, error) {
+	entries, err := os.ReadDir(RuntimeDir())
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read runtime directory: %w", err)
+	}
+
+	var instances []Instance
+	for _, entry := range entries {
+		if filepath.Ext(entry.Name()) != ".json" {
+			continue
+		}
+
+		instance, err := readInstance(filepath.Join(RuntimeDir(), entry.Name()))
+		if err != nil {
+			continue // skip unreadable metadata
+		}
+
+		if !isSocketAlive(instance.Socket) {
+			continue
+		}
+
+		instances = append(instances, instance)
+	}
+
+	slices.SortFunc(instances, func(a, b Instance) int {
+		return strings.Compare(a.Root, b.Root)
+	})
+
+	return instances, nil
+}
+
+// instanceKey identifies a project root inside the runtime directory. The
+// root is hashed to keep socket paths short and free of special characters.
+func instanceKey(root string) string {
+	sum := sha256.Sum256([]byte(root))
+	return hex.EncodeToString(sum[:8])
+}
+
+// metadataPath returns the path of the file describing the instance for a
+// project root.
+func metadataPath(root string) string {
+	return filepath.Join(RuntimeDir(), instanceKey(root)+".json")
+}
+
+func writeInstance(instance Instance) error {
+	data, err := json.Marshal(&instance)
+	if err != nil {
+		return fmt.Errorf("failed to marshal instance metadata: %w", err)
+	}
+
+	return os.WriteFile(metadataPath(instance.Root), data, 0o600)
+}
+
+func readInstance(path string) (Instance, error) {
+	instance := Instance{}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return instance, err
+	}
+
+	err = json.Unmarshal(data, &instance)
+	return instance, err
+}
+
+// isSocketAlive checks if a server is accepting connections on socketPath.
+func isSocketAlive(socketPath string) bool {
+	conn, err := net.DialTimeout("unix", socketPath, time.Second)
+	if err != nil {
+		return false
+	}
+	conn.Close()
+
+	return true
+}
+
+// canonicalPath returns the absolute path of dir with symlinks resolved, so
+// the same directory always maps to the same instance.
+func canonicalPath(dir string) (string, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", fmt.Errorf("failed to get absolute path: %w", err)
+	}
+
+	resolved, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		return dir, nil // keep the absolute path of directories that don't exist
+	}
+
+	return resolved, nil
+}
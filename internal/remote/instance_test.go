@@ -0,0 +1,131 @@
+package remote
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// makeProject creates a git project with a nested directory, returning the
+// canonical project root and the nested directory.
+func makeProject(t *testing.T) (string, string) {
+	root, err := filepath.EvalSymlinks(t.TempDir())
+	require.NoError(t, err)
+
+	require.NoError(t, os.Mkdir(filepath.Join(root, ".git"), 0o755))
+
+	nested := filepath.Join(root, "a", "b")
+	require.NoError(t, os.MkdirAll(nested, 0o755))
+
+	return root, nested
+}
+
+func TestInstance(t *testing.T) {
+	t.Parallel()
+
+	t.Run("ProjectRoot", func(t *testing.T) {
+		t.Parallel()
+
+		t.Run("inside a git repository", func(t *testing.T) {
+			t.Parallel()
+
+			root, nested := makeProject(t)
+
+			actual, err := ProjectRoot(nested)
+			require.NoError(t, err)
+			assert.Equal(t, root, actual)
+		})
+
+		t.Run("outside a git repository", func(t *testing.T) {
+			t.Parallel()
+
+			dir, err := filepath.EvalSymlinks(t.TempDir())
+			require.NoError(t, err)
+
+			actual, err := ProjectRoot(dir)
+			require.NoError(t, err)
+			assert.Equal(t, dir, actual)
+		})
+	})
+
+	t.Run("SocketPath", func(t *testing.T) {
+		t.Parallel()
+
+		socketPath := SocketPath("/some/project")
+		assert.Equal(t, RuntimeDir(), filepath.Dir(socketPath))
+		assert.NotEqual(t, socketPath, SocketPath("/some/other/project"))
+	})
+
+	t.Run("client finds the instance from a nested directory", func(t *testing.T) {
+		t.Parallel()
+
+		root, nested := makeProject(t)
+
+		events := make(chan tea.Msg, 1)
+		server, err := NewServer(root, events)
+		require.NoError(t, err)
+		go server.Run()
+		defer server.Close()
+		fakeApp(t, events, nil)
+
+		client, err := NewClient(nested)
+		require.NoError(t, err)
+		assert.Equal(t, server.SocketPath(), client.SocketPath())
+
+		err = client.SendRequest(Request{Command: "run"})
+		require.NoError(t, err)
+	})
+
+	t.Run("client finds the instance from a subdirectory outside git", func(t *testing.T) {
+		t.Parallel()
+
+		dir, err := filepath.EvalSymlinks(t.TempDir())
+		require.NoError(t, err)
+		nested := filepath.Join(dir, "a", "b")
+		require.NoError(t, os.MkdirAll(nested, 0o755))
+
+		events := make(chan tea.Msg, 1)
+		server, err := NewServer(dir, events)
+		require.NoError(t, err)
+		go server.Run()
+		defer server.Close()
+		fakeApp(t, events, nil)
+
+		client, err := NewClient(nested)
+		require.NoError(t, err)
+		assert.Equal(t, server.SocketPath(), client.SocketPath())
+		require.NoError(t, client.SendRequest(Request{Command: "run"}))
+	})
+
+	t.Run("ListInstances", func(t *testing.T) {
+		t.Parallel()
+
+		root, _ := makeProject(t)
+
+		server, err := NewServer(root, make(chan tea.Msg))
+		require.NoError(t, err)
+		go server.Run()
+
+		instances, err := ListInstances()
+		require.NoError(t, err)
+		assert.Contains(t, roots(instances), root)
+
+		server.Close()
+
+		instances, err = ListInstances()
+		require.NoError(t, err)
+		assert.NotContains(t, roots(instances), root)
+	})
+}
+
+func roots(instances []Instance) []string {
+	var result []string
+	for _, instance := range instances {
+		result = append(result, instance.Root)
+	}
+	return result
+}
@@ -0,0 +1,127 @@
+package remote
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+var (
+	errLocked    = errors.New("lock is held by another process")
+	errStaleLock = errors.New("lock file was removed")
+)
+
+// InstanceRunningError is returned when starting a server for a project that
+// already has a running instance.
+type InstanceRunningError struct {
+	Root string
+	PID  int
+}
+
+func (e *InstanceRunningError) Error() string {
+	owner := "another process"
+	if e.PID > 0 {
+		owner = "pid " + strconv.Itoa(e.PID)
+	}
+
+	return fmt.Sprintf(
+		"mark is already running for %s (%s).\nRun `mark attach` to open it here, or stop it before starting a new one.",
+		e.Root, owner,
+	)
+}
+
+// instanceLock guarantees that a single instance runs per project root. It
+// is an exclusive lock on a file next to the socket containing the pid of
+// the owner. The lock is released by the OS if the process dies, which makes
+// it possible to tell a stale socket from a live one.
+type instanceLock struct {
+	file *os.File
+}
+
+// lockPath returns the path of the lock file for a project root.
+func lockPath(root string) string {
+	return filepath.Join(RuntimeDir(), instanceKey(root)+".lock")
+}
+
+// acquireLock takes the instance lock for a project root. Returns an
+// InstanceRunningError if another instance holds it.
+func acquireLock(root string) (*instanceLock, error) {
+	path := lockPath(root)
+
+	for {
+		file, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0o600)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open lock file: %w", err)
+		}
+
+		err = lockFile(file, path)
+		if errors.Is(err, errStaleLock) {
+			// the owner released the lock while we opened the file, try
+			// again with the file now at path
+			file.Close()
+			continue
+		}
+		if errors.Is(err, errLocked) {
+			pid := readPID(file)
+			file.Close()
+			return nil, &InstanceRunningError{Root: root, PID: pid}
+		}
+		if err != nil {
+			file.Close()
+			return nil, fmt.Errorf("failed to lock %s: %w", file.Name(), err)
+		}
+
+		// record the owner for helpful error messages in other instances
+		if err := file.Truncate(0); err == nil {
+			file.WriteAt([]byte(strconv.Itoa(os.Getpid())), 0)
+		}
+
+		return &instanceLock{file: file}, nil
+	}
+}
+
+// lockFile locks file, opened at path. Returns errStaleLock if file was
+// removed from path before it was locked: its lock protects nothing since
+// another process may create and lock a new file at path.
+func lockFile(file *os.File, path string) error {
+	if err := tryLock(file); err != nil {
+		return err
+	}
+
+	locked, err := file.Stat()
+	if err != nil {
+		return err
+	}
+	current, err := os.Stat(path)
+	if errors.Is(err, os.ErrNotExist) || (err == nil && !os.SameFile(locked, current)) {
+		return errStaleLock
+	}
+	return err
+}
+
+// release removes the lock file and releases the lock. Processes which
+// opened the file before its removal lock a file no longer at the path,
+// which lockFile detects.
+func (l *instanceLock) release() {
+	os.Remove(l.file.Name())
+	l.file.Close()
+}
+
+// readPID reads the pid of the lock owner, returning 0 when unknown.
+func readPID(file *os.File) int {
+	data, err := io.ReadAll(io.NewSectionReader(file, 0, 32))
+	if err != nil {
+		return 0
+	}
+
+	pid, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil {
+		return 0
+	}
+
+	return pid
+}
@@ -0,0 +1,11 @@
+//go:build !unix
+
+package remote
+
+import "os"
+
+// tryLock is a no-op on platforms without flock. Running instances are
+// still detected by probing their socket.
+func tryLock(file *os.File) error {
+	return nil
+}
@@ -0,0 +1,106 @@
+package remote
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSingleInstance(t *testing.T) {
+	t.Parallel()
+
+	t.Run("refuses a second instance", func(t *testing.T) {
+		t.Parallel()
+
+		root, nested := makeProject(t)
+
+		server, err := NewServer(root, make(chan tea.Msg))
+		require.NoError(t, err)
+		defer server.Close()
+
+		_, err = NewServer(nested, make(chan tea.Msg))
+		require.Error(t, err)
+
+		var running *InstanceRunningError
+		require.ErrorAs(t, err, &running)
+		assert.Equal(t, root, running.Root)
+		assert.Equal(t, os.Getpid(), running.PID)
+		assert.Contains(t, err.Error(), "mark is already running for "+root)
+	})
+
+	t.Run("recovers from a stale socket", func(t *testing.T) {
+		t.Parallel()
+
+		root, _ := makeProject(t)
+		socketPath := SocketPath(root)
+		require.NoError(t, os.MkdirAll(filepath.Dir(socketPath), 0o700))
+
+		// simulate a crashed instance: the socket file exists but nobody listens
+		listener, err := net.Listen("unix", socketPath)
+		require.NoError(t, err)
+		listener.(*net.UnixListener).SetUnlinkOnClose(false)
+		listener.Close()
+		require.FileExists(t, socketPath)
+
+		events := make(chan tea.Msg, 1)
+		server, err := NewServer(root, events)
+		require.NoError(t, err)
+		go server.Run()
+		defer server.Close()
+		fakeApp(t, events, nil)
+
+		client, err := NewClient(root)
+		require.NoError(t, err)
+		require.NoError(t, client.SendRequest(Request{Command: "run"}))
+	})
+
+	t.Run("Close removes the socket and allows a new instance", func(t *testing.T) {
+		t.Parallel()
+
+		root, _ := makeProject(t)
+
+		server, err := NewServer(root, make(chan tea.Msg))
+		require.NoError(t, err)
+		require.FileExists(t, server.SocketPath())
+
+		require.NoError(t, server.Close())
+		require.NoError(t, server.Close())
+		assert.NoFileExists(t, server.SocketPath())
+		assert.NoFileExists(t, lockPath(root))
+
+		server, err = NewServer(root, make(chan tea.Msg))
+		require.NoError(t, err)
+		server.Close()
+	})
+
+	t.Run("a lock file opened before its release is not locked", func(t *testing.T) {
+		t.Parallel()
+
+		root, _ := makeProject(t)
+
+		lock, err := acquireLock(root)
+		require.NoError(t, err)
+
+		// another process opens the lock file, then the owner releases it
+		stale, err := os.Open(lockPath(root))
+		require.NoError(t, err)
+		defer stale.Close()
+		lock.release()
+
+		lock, err = acquireLock(root)
+		require.NoError(t, err)
+		defer lock.release()
+
+		// locking the removed file must not make a second owner
+		assert.ErrorIs(t, lockFile(stale, lockPath(root)), errStaleLock)
+
+		_, err = acquireLock(root)
+		var running *InstanceRunningError
+		assert.ErrorAs(t, err, &running)
+	})
+}
@@ -0,0 +1,13 @@
+//go:build !linux && !darwin
+
+package remote
+
+import (
+	"net"
+)
+
+// peerUID is not supported on this platform. Access to the socket is only
+// restricted by the permissions of the socket and its directory.
+func peerUID(conn *net.UnixConn) (int, error) {
+	return 0, errPeerCredUnsupported
+}
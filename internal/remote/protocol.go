@@ -0,0 +1,140 @@
+package remote
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"slices"
+)
+
+// Messages exchanged over the socket are framed: every frame starts with a
+// 4 byte big endian length followed by that many bytes of JSON. This allows
+// payloads of any size (like a large diff read from stdin) to be sent
+// without relying on line delimiters.
+
+// ProtocolVersion is the version of the protocol spoken over the socket. It
+// must be bumped on incompatible changes only: new commands are detected
+// through the command list exchanged in the handshake.
+const ProtocolVersion = 1
+
+// DefaultMaxRequestSize is the maximum size of a request frame accepted by
+// the server unless configured otherwise.
+const DefaultMaxRequestSize = 64 * 1024 * 1024 // 64MB
+
+// frameHeaderSize is the size of the length prefix of a frame.
+const frameHeaderSize = 4
+
+// Hello is the first message sent in each direction on a new connection.
+// The client sends its protocol version, the server answers with its own
+// version and the commands it supports. The server closes the connection
+// after answering when the versions don't match.
+type Hello struct {
+	ProtocolVersion int      `json:"protocol_version"`
+	Commands        []string `json:"commands,omitempty"`
+	Error           string   `json:"error,omitempty"`
+}
+
+// Supports checks if the commands announced in the handshake include command.
+func (hello Hello) Supports(command string) bool {
+	return slices.Contains(hello.Commands, command)
+}
+
+// VersionMismatchError is returned when the client and the server speak
+// different versions of the protocol.
+type VersionMismatchError struct {
+	Client int
+	Server int
+}
+
+func (e *VersionMismatchError) Error() string {
+	return fmt.Sprintf(
+		"protocol version mismatch: this mark speaks version %d but the running instance speaks version %d.\nRestart the running instance so both use the same version of mark.",
+		e.Client, e.Server,
+	)
+}
+
+// UnsupportedCommandError is returned when the running instance doesn't
+// know a command, usually because it is older than the CLI.
+type UnsupportedCommandError struct {
+	Command string
+}
+
+func (e *UnsupportedCommandError) Error() string {
+	return fmt.Sprintf(
+		"the running mark instance doesn't support the command %q.\nRestart it to use the latest version of mark.",
+		e.Command,
+	)
+}
+
+// Response is sent by the server after processing each Request.
+type Response struct {
+	Output string `json:"output,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// FrameTooLargeError is returned when a frame exceeds the maximum size.
+type FrameTooLargeError struct {
+	Size int
+	Max  int
+}
+
+func (e *FrameTooLargeError) Error() string {
+	return fmt.Sprintf("request too large: %d bytes (maximum is %d bytes)", e.Size, e.Max)
+}
+
+// writeFrame marshals v as JSON and writes it as a single frame.
+func writeFrame(w io.Writer, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("failed to marshal message: %w", err)
+	}
+
+	if uint64(len(data)) > math.MaxUint32 {
+		return fmt.Errorf("message too large to be framed: %d bytes", len(data))
+	}
+
+	header := make([]byte, frameHeaderSize)
+	binary.BigEndian.PutUint32(header, uint32(len(data)))
+
+	if _, err := w.Write(header); err != nil {
+		return err
+	}
+	if _, err := w.Write(data); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// readFrame reads a single frame and unmarshals its JSON content into v.
+// Frames larger than maxSize are rejected with a FrameTooLargeError without
+// reading their content. Returns io.EOF when the connection is closed
+// before a new frame starts.
+func readFrame(r io.Reader, maxSize int, v any) error {
+	header := make([]byte, frameHeaderSize)
+	if _, err := io.ReadFull(r, header); err != nil {
+		if errors.Is(err, io.ErrUnexpectedEOF) {
+			return fmt.Errorf("failed to read frame header: %w", err)
+		}
+		return err
+	}
+
+	size := binary.BigEndian.Uint32(header)
+	if uint64(size) > uint64(maxSize) {
+		return &FrameTooLargeError{Size: int(size), Max: maxSize}
+	}
+
+	data := make([]byte, size)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return fmt.Errorf("failed to read frame: %w", err)
+	}
+
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("failed to parse frame: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,120 @@
+package remote
+
+import (
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"mark/internal/messages"
+
+	tea "github.com/charmbracelet/bubbletea/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandshake(t *testing.T) {
+	t.Parallel()
+
+	t.Run("server announces its commands", func(t *testing.T) {
+		t.Parallel()
+
+		root, _ := makeProject(t)
+
+		server, err := NewServer(root, make(chan tea.Msg))
+		require.NoError(t, err)
+		go server.Run()
+		defer server.Close()
+
+		client, err := NewClient(root)
+		require.NoError(t, err)
+
+		hello, err := client.Hello()
+		require.NoError(t, err)
+		assert.Equal(t, ProtocolVersion, hello.ProtocolVersion)
+		assert.Subset(t, hello.Commands, messages.Commands())
+		assert.True(t, hello.Supports(AttachCommand))
+		assert.True(t, hello.Supports("run"))
+		assert.False(t, hello.Supports("test-message"))
+	})
+
+	t.Run("client refuses unsupported commands", func(t *testing.T) {
+		t.Parallel()
+
+		root, _ := makeProject(t)
+
+		events := make(chan tea.Msg, 1)
+		server, err := NewServer(root, events)
+		require.NoError(t, err)
+		go server.Run()
+		defer server.Close()
+
+		client, err := NewClient(root)
+		require.NoError(t, err)
+
+		err = client.SendRequest(Request{Command: "test-message"})
+		var unsupported *UnsupportedCommandError
+		require.ErrorAs(t, err, &unsupported)
+		assert.Equal(t, "test-message", unsupported.Command)
+		assert.Empty(t, events)
+	})
+
+	t.Run("server closes connections with a different version", func(t *testing.T) {
+		t.Parallel()
+
+		root, _ := makeProject(t)
+
+		server, err := NewServer(root, make(chan tea.Msg))
+		require.NoError(t, err)
+		go server.Run()
+		defer server.Close()
+
+		conn, err := net.Dial("unix", server.SocketPath())
+		require.NoError(t, err)
+		defer conn.Close()
+
+		require.NoError(t, writeFrame(conn, &Hello{ProtocolVersion: ProtocolVersion + 1}))
+
+		hello := Hello{}
+		require.NoError(t, readFrame(conn, DefaultMaxRequestSize, &hello))
+		assert.Equal(t, ProtocolVersion, hello.ProtocolVersion)
+
+		err = readFrame(conn, DefaultMaxRequestSize, &Response{})
+		assert.ErrorIs(t, err, io.EOF)
+	})
+
+	t.Run("client fails when the server speaks a different version", func(t *testing.T) {
+		t.Parallel()
+
+		root, _ := makeProject(t)
+
+		// fake an instance speaking a future version of the protocol
+		socketPath := SocketPath(root)
+		require.NoError(t, os.MkdirAll(filepath.Dir(socketPath), 0o700))
+		listener, err := net.Listen("unix", socketPath)
+		require.NoError(t, err)
+		defer listener.Close()
+
+		go func() {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+
+			readFrame(conn, DefaultMaxRequestSize, &Hello{})
+			writeFrame(conn, &Hello{ProtocolVersion: ProtocolVersion + 1})
+		}()
+
+		client, err := NewClient(root)
+		require.NoError(t, err)
+
+		err = client.SendRequest(Request{Command: "run"})
+		var mismatch *VersionMismatchError
+		require.ErrorAs(t, err, &mismatch)
+		assert.Equal(t, ProtocolVersion, mismatch.Client)
+		assert.Equal(t, ProtocolVersion+1, mismatch.Server)
+		assert.Contains(t, err.Error(), "protocol version mismatch")
+	})
+}
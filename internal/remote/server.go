@@ -1,12 +1,14 @@
 package remote
 
 import (
-	"bufio"
-	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"net"
 	"os"
-	"path"
+	"path/filepath"
+	"sync"
+	"time"
 
 	"mark/internal/app"
 	"mark/internal/messages"
@@ -15,64 +17,251 @@ import (
 )
 
 type Server struct {
-	listener net.Listener
-	events   chan tea.Msg
+	listener       net.Listener
+	lock           *instanceLock
+	instance       Instance
+	events         chan tea.Msg
+	maxRequestSize int
+	token          string
+	closeOnce      sync.Once
+
+	views            views
+	followClientSize bool
 }
 
+// NewServer creates a server listening on the socket of the project
+// containing cwd. The socket is created in the RuntimeDir, keyed by the
+// project root, so clients anywhere inside the project can find it.
+//
+// Only one server can run per project. An InstanceRunningError is returned
+// if another instance is alive, while sockets left behind by instances that
+// crashed are removed.
 func NewServer(cwd string, events chan tea.Msg) (*Server, error) {
 	// determine socket path
-	socketPath := path.Join(cwd, ".local", "share", "mark", "socket")
+	root, err := ProjectRoot(cwd)
+	if err != nil {
+		return nil, fmt.Errorf("failed to determine project root: %w", err)
+	}
+	socketPath := SocketPath(root)
 
 	// create the directory if it doesn't exist
-	if err := os.MkdirAll(path.Dir(socketPath), 0o755); err != nil {
-		return nil, fmt.Errorf("failed to create directory for socket file: %w", err)
+	if err := ensurePrivateDir(filepath.Dir(socketPath)); err != nil {
+		return nil, err
+	}
+
+	// make sure this is the only instance for the project
+	lock, err := acquireLock(root)
+	if err != nil {
+		return nil, err
+	}
+
+	// remove the socket of a previous instance that didn't exit cleanly
+	if err := removeStaleSocket(root, socketPath); err != nil {
+		lock.release()
+		return nil, err
 	}
 
 	// create socket file
 	listener, err := net.Listen("unix", socketPath)
 	if err != nil {
+		lock.release()
 		return nil, fmt.Errorf("failed to list in socket: %w", err)
 	}
 
+	if err := restrictSocket(socketPath); err != nil {
+		listener.Close()
+		lock.release()
+		return nil, err
+	}
+
+	instance := Instance{
+		Root:      root,
+		Socket:    socketPath,
+		PID:       os.Getpid(),
+		StartedAt: time.Now().UTC(),
+	}
+
+	// register the instance so it can be discovered
+	if err := writeInstance(instance); err != nil {
+		listener.Close()
+		lock.release()
+		return nil, fmt.Errorf("failed to write instance metadata: %w", err)
+	}
+
 	server := &Server{
-		listener: listener,
-		events:   events,
+		listener:       listener,
+		lock:           lock,
+		instance:       instance,
+		events:         events,
+		maxRequestSize: DefaultMaxRequestSize,
 	}
 
 	return server, nil
 }
 
+// removeStaleSocket removes the socket and metadata left behind by an
+// instance that is no longer running. Must be called while holding the
+// instance lock.
+func removeStaleSocket(root string, socketPath string) error {
+	if _, err := os.Stat(socketPath); err != nil {
+		return nil // nothing to clean up
+	}
+
+	// a process that doesn't use the lock may still be serving the socket
+	if isSocketAlive(socketPath) {
+		return &InstanceRunningError{Root: root}
+	}
+
+	if err := os.Remove(socketPath); err != nil {
+		return fmt.Errorf("failed to remove stale socket: %w", err)
+	}
+	os.Remove(metadataPath(root))
+
+	return nil
+}
+
+// SocketPath returns the path of the socket the server listens on.
+func (s *Server) SocketPath() string {
+	return s.instance.Socket
+}
+
+// SetMaxRequestSize sets the maximum size in bytes of a request accepted by
+// the server. Larger requests are rejected with an error sent to the client.
+func (s *Server) SetMaxRequestSize(size int) {
+	s.maxRequestSize = size
+}
+
+// SetToken requires every request to carry token. Used to restrict which
+// scripts can control the instance.
+func (s *Server) SetToken(token string) {
+	s.token = token
+}
+
 func (s *Server) Run() {
 	for {
 		// accept a connection from the socket
 		conn, err := s.listener.Accept()
 		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return // server was closed
+			}
 			s.events <- app.ErrMsg{Err: fmt.Errorf("failed to accept socket connection: %w", err)}
 			return // TODO recover from listening failure
 		}
-		defer conn.Close()
-
-		// read messages from the connection
-		scanner := bufio.NewScanner(conn)
-		for scanner.Scan() {
-			// parse JSON
-			req := Request{}
-			err := json.Unmarshal(scanner.Bytes(), &req)
-			if err != nil {
-				s.events <- app.ErrMsg{Err: fmt.Errorf("failed to parse client request: %w", err)}
-				continue // skip to the next message
+
+		go s.handleConnection(conn)
+	}
+}
+
+// handleConnection reads requests from the connection until it is closed,
+// replying to each one with a Response.
+func (s *Server) handleConnection(conn net.Conn) {
+	defer conn.Close()
+
+	if err := authorizePeer(conn); err != nil {
+		_ = writeFrame(conn, &Hello{ProtocolVersion: ProtocolVersion, Error: err.Error()})
+		return
+	}
+
+	if !s.handshake(conn) {
+		return
+	}
+
+	for {
+		req := Request{}
+		err := readFrame(conn, s.maxRequestSize, &req)
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return // client closed the connection
+			}
+
+			var tooLarge *FrameTooLargeError
+			if errors.As(err, &tooLarge) {
+				// discard the payload so the client can read the response
+				if _, err := io.CopyN(io.Discard, conn, int64(tooLarge.Size)); err != nil {
+					return
+				}
+				s.reply(conn, tooLarge)
+				continue
 			}
 
-			msg := messages.ToTeaMsg(req.Command, req.Args, req.Stdin)
+			s.events <- app.ErrMsg{Err: fmt.Errorf("failed to parse client request: %w", err)}
+			return // the stream is out of sync, drop the connection
+		}
+
+		if err := authorizeToken(s.token, req.Token); err != nil {
+			s.reply(conn, err)
+			continue
+		}
+
+		if req.Command == AttachCommand {
+			s.serveAttach(conn)
+			return
+		}
+
+		msg := messages.ToTeaMsg(req.Command, req.Args, req.Stdin)
+
+		// wait for the App to handle the command, or for the output of queries
+		query, ok := msg.(app.Query)
+		if !ok {
+			command := app.NewCommandMsg(msg)
+			msg, query = command, command
+		}
+
+		s.events <- msg
+		result := <-query.Result()
 
-			s.events <- msg
+		res := Response{Output: result.Output}
+		if result.Err != nil {
+			res.Error = result.Err.Error()
 		}
+
+		// a failure to reply means the client is gone, there's nobody to report to
+		_ = writeFrame(conn, &res)
 	}
 }
 
-func (s *Server) Close() error {
-	if s.listener != nil {
-		return s.listener.Close()
+// handshake exchanges Hello messages with the client. Returns false if the
+// connection can't be used.
+func (s *Server) handshake(conn net.Conn) bool {
+	hello := Hello{}
+	if err := readFrame(conn, s.maxRequestSize, &hello); err != nil {
+		return false
 	}
-	return nil
+
+	res := Hello{
+		ProtocolVersion: ProtocolVersion,
+		Commands:        append(messages.Commands(), AttachCommand),
+	}
+	if err := writeFrame(conn, &res); err != nil {
+		return false
+	}
+
+	// the client reports the mismatch to the user
+	return hello.ProtocolVersion == ProtocolVersion
+}
+
+// reply sends a Response to the client reporting err.
+func (s *Server) reply(conn net.Conn, err error) {
+	res := Response{Error: err.Error()}
+
+	// a failure to reply means the client is gone, there's nobody to report to
+	_ = writeFrame(conn, &res)
+}
+
+// Close stops the server, removing its socket and releasing the instance
+// lock. It is safe to call Close more than once.
+func (s *Server) Close() error {
+	var err error
+
+	s.closeOnce.Do(func() {
+		os.Remove(metadataPath(s.instance.Root))
+
+		// closing the listener also removes the socket file
+		err = s.listener.Close()
+
+		s.lock.release()
+	})
+
+	return err
 }